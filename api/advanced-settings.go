@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"reflect"
 )
 
 // AdvancedGameSettings represents advanced game rules and player settings
@@ -41,6 +42,34 @@ type AdvancedGameSettings struct {
 
 	// FlightMode enables the ability to fly around the game world. Boolean represented as a string.
 	FlightMode string `json:"FG.PlayerRules.FlightMode,omitempty"`
+
+	// Extra holds the settings that have no field, such as those added by game updates or mods,
+	// keyed by their FG.* wire key. They are kept when decoding and sent back when encoding.
+	Extra map[string]string `json:"-"`
+}
+
+// advancedGameSettingKeys are the wire keys of the AdvancedGameSettings fields.
+var advancedGameSettingKeys = jsonKeys(reflect.TypeFor[AdvancedGameSettings]())
+
+// MarshalJSON encodes the settings with their FG.* wire keys, including the Extra settings.
+func (s AdvancedGameSettings) MarshalJSON() ([]byte, error) {
+	type plain AdvancedGameSettings
+	return marshalWithExtra(plain(s), s.Extra)
+}
+
+// UnmarshalJSON decodes settings with their FG.* wire keys, keeping unknown keys in Extra.
+func (s *AdvancedGameSettings) UnmarshalJSON(data []byte) error {
+	type plain AdvancedGameSettings
+	if err := json.Unmarshal(data, (*plain)(s)); err != nil {
+		return err
+	}
+
+	extra, err := unmarshalExtra(data, advancedGameSettingKeys)
+	if err != nil {
+		return err
+	}
+	s.Extra = extra
+	return nil
 }
 
 // AdvancedGameSettingsData wraps the applied advanced game settings data
@@ -64,46 +93,32 @@ type ApplyAdvancedGameSettingsRequest struct {
 	Function string `json:"function"`
 
 	// Data contains the advanced game settings to apply.
-	Data AdvancedGameSettings `json:"appliedAdvancedGameSettings"`
+	Data AppliedAdvancedGameSettings `json:"data"`
+}
+
+// GetAdvancedGameSettingsData holds the advanced game settings of the running session.
+type GetAdvancedGameSettingsData struct {
+	// CreativeModeEnabled indicates whether advanced game settings are enabled for the session.
+	CreativeModeEnabled bool `json:"creativeModeEnabled"`
+
+	// Settings contains the advanced game rules and player settings.
+	Settings AdvancedGameSettings `json:"advancedGameSettings"`
 }
 
 // GetAdvancedGameSettings retrieves the currently applied advanced game settings
 // from the active Satisfactory save file.
 func (c *GoFactoryClient) GetAdvancedGameSettings(ctx context.Context) (*AdvancedGameSettings, error) {
-	appliedAdvanceSettingsResponse, err := CreateAndSendPostRequest[AppliedAdvancedGameSettings](ctx, c,
-		GetAdvancedGameSettingsFunction,
-		CreateGenericFunctionBody(GetAdvancedGameSettingsFunction))
+	response, err := CallFunction[Empty, GetAdvancedGameSettingsData](ctx, c, GetAdvancedGameSettingsFunction, Empty{})
 	if err != nil {
 		return nil, err
 	}
-	return &appliedAdvanceSettingsResponse.Settings, nil
+	return &response.Settings, nil
 }
 
 // ApplyAdvancedGameSettings applies the provided AdvancedGameSettings
 // to the current Satisfactory save file.
 func (c *GoFactoryClient) ApplyAdvancedGameSettings(ctx context.Context, settings AdvancedGameSettings) error {
-	functionBody, err := json.Marshal(ApplyAdvancedGameSettingsRequest{
-		Function: ApplyAdvancedGameSettingsFunction,
-		Data:     settings,
-	})
-	if err != nil {
-		return err
-	}
-
-	request, err := c.CreatePostRequest(ApplyAdvancedGameSettingsFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	var apiError APIError
-	err = c.SendPostRequest(ctx, request, &apiError)
-	if err != nil {
-		return err
-	}
-
-	if apiError != (APIError{}) {
-		return &apiError
-	}
-
-	return nil
+	_, err := CallFunction[AppliedAdvancedGameSettings, Empty](ctx, c, ApplyAdvancedGameSettingsFunction,
+		AppliedAdvancedGameSettings{Settings: settings})
+	return err
 }
@@ -0,0 +1,210 @@
+package apitest
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/alchemicalkube/gofactory/api"
+)
+
+// privilegeSatisfies reports whether a caller holding privilege may call a function requiring required.
+func privilegeSatisfies(privilege string, required string) bool {
+	return api.Privilege(privilege).Satisfies(api.Privilege(required))
+}
+
+// privilegeAtMostClient reports whether a Client token satisfies privilege, which InitialAdmin never is
+// once the server is claimed.
+func privilegeAtMostClient(privilege string) bool {
+	return api.Privilege(api.CLIENT_PRIVILEGE).Satisfies(api.Privilege(privilege))
+}
+
+// tokenPayload is the JSON payload encoded into the first segment of every issued token.
+type tokenPayload struct {
+	PrivilegeLevel string `json:"pl"`
+}
+
+// mintToken creates a token in the dedicated server's format: a base64 encoded JSON payload
+// holding the privilege level, a dot, and a hex encoded signature.
+func mintToken(privilege string) string {
+	pl := privilege
+	if privilege == api.API_TOKEN_PRIVILEGE {
+		pl = "APIToken"
+	}
+	payload, _ := json.Marshal(tokenPayload{PrivilegeLevel: pl})
+
+	signature := make([]byte, 32)
+	_, _ = rand.Read(signature)
+
+	return base64.StdEncoding.EncodeToString(payload) + "." + hex.EncodeToString(signature)
+}
+
+// issueToken mints a new token for privilege and records it as valid. Must be called with s.mu held.
+func (s *Server) issueToken(privilege string) string {
+	token := mintToken(privilege)
+	s.tokens[token] = privilege
+	return token
+}
+
+// revokeTokens invalidates every issued token holding one of the given privileges. Must be called with s.mu held.
+func (s *Server) revokeTokens(privileges ...string) {
+	for token, privilege := range s.tokens {
+		for _, p := range privileges {
+			if privilege == p {
+				delete(s.tokens, token)
+			}
+		}
+	}
+}
+
+// IssueToken mints a valid token with the given privilege level without going through a login,
+// which is convenient for setting up a test.
+func (s *Server) IssueToken(privilege string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issueToken(privilege)
+}
+
+// RevokeToken invalidates a previously issued token, as if the server had been reinstalled.
+func (s *Server) RevokeToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, token)
+}
+
+// Claim claims the fake server directly with the given name and admin password,
+// returning an Administrator token. It panics if the server is already claimed.
+func (s *Server) Claim(serverName string, adminPassword string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.claimed {
+		panic("apitest: server is already claimed")
+	}
+	s.claim(serverName, adminPassword)
+	return s.issueToken(api.ADMINISTRATOR_PRIVILEGE)
+}
+
+// Claimed reports whether the fake server has been claimed.
+func (s *Server) Claimed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.claimed
+}
+
+// ServerName returns the name the fake server was claimed or renamed with.
+func (s *Server) ServerName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.serverName
+}
+
+// claim marks the server as claimed. Must be called with s.mu held.
+func (s *Server) claim(serverName string, adminPassword string) {
+	s.claimed = true
+	s.serverName = serverName
+	s.adminPassword = adminPassword
+	s.revokeTokens(api.INITIAL_ADMIN_PRIVILEGE)
+}
+
+func (s *Server) verifyAuthenticationToken(call *functionCall) {
+	writeNoContent(call.w)
+}
+
+func (s *Server) passwordlessLogin(call *functionCall) {
+	var data api.PasswordlessLoginRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+
+	var privilege string
+	switch {
+	case !s.claimed:
+		privilege = api.INITIAL_ADMIN_PRIVILEGE
+	case s.clientPassword == "" && privilegeAtMostClient(data.MinimumPrivilegeLevel):
+		privilege = api.CLIENT_PRIVILEGE
+	default:
+		writeError(call.w, http.StatusForbidden, api.ErrorCodePasswordlessLoginNotPossible,
+			"passwordless login is not possible for the requested privilege level")
+		return
+	}
+
+	writeData(call.w, api.PasswordLoginResponseData{AuthToken: s.issueToken(privilege)})
+}
+
+func (s *Server) passwordLogin(call *functionCall) {
+	var data api.PasswordLoginRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+
+	var privilege string
+	switch {
+	case !s.claimed:
+		writeError(call.w, http.StatusForbidden, api.ErrorCodeServerNotClaimed, "the server has not been claimed yet")
+		return
+	case data.Password == s.adminPassword:
+		privilege = api.ADMINISTRATOR_PRIVILEGE
+	case s.clientPassword != "" && data.Password == s.clientPassword &&
+		privilegeAtMostClient(data.MinimumPrivilegeLevel):
+		privilege = api.CLIENT_PRIVILEGE
+	default:
+		writeError(call.w, http.StatusUnauthorized, api.ErrorCodeWrongPassword, "wrong password")
+		return
+	}
+
+	writeData(call.w, api.PasswordLoginResponseData{AuthToken: s.issueToken(privilege)})
+}
+
+func (s *Server) claimServer(call *functionCall) {
+	var data api.ClaimRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+	if s.claimed {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeServerClaimed, "the server has already been claimed")
+		return
+	}
+	if data.ServerName == "" || data.AdminPassword == "" {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeMissingParams, "serverName and adminPassword are required")
+		return
+	}
+
+	s.claim(data.ServerName, data.AdminPassword)
+	writeData(call.w, api.ClaimResponseData{AuthenticationToken: s.issueToken(api.ADMINISTRATOR_PRIVILEGE)})
+}
+
+func (s *Server) setClientPassword(call *functionCall) {
+	var data api.ClientPasswordRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+	if data.Password != "" && data.Password == s.adminPassword {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodePasswordInUse, "the client password cannot match the admin password")
+		return
+	}
+
+	s.clientPassword = data.Password
+	s.revokeTokens(api.CLIENT_PRIVILEGE)
+	writeNoContent(call.w)
+}
+
+func (s *Server) setAdminPassword(call *functionCall) {
+	var data api.AdminPasswordRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+	if data.Password == "" {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeCannotResetAdminPassword, "the admin password cannot be removed")
+		return
+	}
+	if data.Password == s.clientPassword {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodePasswordInUse, "the admin password cannot match the client password")
+		return
+	}
+
+	s.adminPassword = data.Password
+	s.revokeTokens(api.CLIENT_PRIVILEGE, api.ADMINISTRATOR_PRIVILEGE)
+	writeData(call.w, api.AdminPasswordResponse{AuthToken: s.issueToken(api.ADMINISTRATOR_PRIVILEGE)})
+}
@@ -0,0 +1,320 @@
+// Package apitest provides an in-memory fake of the Satisfactory dedicated server HTTPS API
+// for use in tests of code built on top of the api package.
+package apitest
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/alchemicalkube/gofactory/api"
+)
+
+// Server is a stateful, in-memory fake of the Satisfactory dedicated server HTTPS API.
+// It serves every function in the api package over TLS through an httptest.Server and
+// keeps track of claiming, issued tokens, passwords, sessions, saves and server options.
+//
+// All exported methods are safe to call while the server is handling requests.
+type Server struct {
+	// URL is the base URL of the fake server, in the form https://ipaddr:port.
+	URL string
+
+	httpServer *httptest.Server
+
+	mu                   sync.Mutex
+	claimed              bool
+	serverName           string
+	adminPassword        string
+	clientPassword       string
+	tokens               map[string]string
+	health               string
+	serverCustomData     string
+	autoLoadSessionName  string
+	activeSessionName    string
+	gameRunning          bool
+	sessions             []*session
+	serverOptions        map[string]string
+	pendingServerOptions map[string]string
+	creativeModeEnabled  bool
+	advancedGameSettings map[string]string
+	commands             map[string]CommandHandler
+	shutdowns            int
+	failures             int
+	failureStatus        int
+}
+
+// CommandHandler produces the console output for a command run through the RunCommand function.
+type CommandHandler func(command string) string
+
+// NewServer starts a new fake server in the same state as a freshly installed,
+// unclaimed dedicated server. The caller must call Close when finished.
+func NewServer() *Server {
+	s := &Server{
+		tokens:               make(map[string]string),
+		health:               "healthy",
+		serverOptions:        defaultServerOptions(),
+		pendingServerOptions: make(map[string]string),
+		advancedGameSettings: defaultAdvancedGameSettings(),
+		commands:             make(map[string]CommandHandler),
+	}
+	s.registerDefaultCommands()
+	s.httpServer = httptest.NewTLSServer(s)
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// Close shuts down the fake server and blocks until all outstanding requests have completed.
+func (s *Server) Close() {
+	s.httpServer.Close()
+}
+
+// NewClient returns a *api.GoFactoryClient pointed at the fake server, using the given token
+// and any additional options. The client trusts the fake server's self-signed certificate.
+func (s *Server) NewClient(token string, opts ...api.Option) *api.GoFactoryClient {
+	opts = append([]api.Option{api.WithAuthToken(token), api.WithHTTPClient(s.httpServer.Client())}, opts...)
+	return api.NewClient(s.URL, opts...)
+}
+
+// HTTPClient returns a *http.Client configured to trust the fake server's certificate.
+func (s *Server) HTTPClient() *http.Client {
+	return s.httpServer.Client()
+}
+
+// Certificate returns the fake server's self-signed certificate, such as to pin its fingerprint.
+func (s *Server) Certificate() *x509.Certificate {
+	return s.httpServer.Certificate()
+}
+
+// SetHealth sets the health status and custom data returned by the HealthCheck function.
+func (s *Server) SetHealth(health string, serverCustomData string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health = health
+	s.serverCustomData = serverCustomData
+}
+
+// HandleCommand registers the handler used to produce console output for the given command
+// when it is run through the RunCommand function. Command names are matched case-insensitively
+// against the first word of the command. The handler is called while the server state is locked,
+// so it must not call methods on the Server.
+func (s *Server) HandleCommand(command string, handler CommandHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands[strings.ToLower(command)] = handler
+}
+
+// Shutdowns returns how many times the Shutdown function has been called on the fake server.
+func (s *Server) Shutdowns() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shutdowns
+}
+
+// FailNext makes the fake server answer the next n API calls with the given HTTP status and a
+// plain text body, like a dedicated server that is restarting or still loading a save.
+func (s *Server) FailNext(n int, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = n
+	s.failureStatus = status
+}
+
+// functionCall holds everything a function handler needs to serve a single API call.
+type functionCall struct {
+	w         http.ResponseWriter
+	r         *http.Request
+	data      json.RawMessage
+	token     string
+	privilege string
+	saveFile  []byte
+}
+
+// functionHandler describes how the fake server handles a single API function.
+type functionHandler struct {
+	// privilege is the minimum privilege level required to call the function.
+	privilege string
+
+	// handle serves the call. It is called with Server.mu held.
+	handle func(s *Server, call *functionCall)
+}
+
+var functionHandlers = map[string]functionHandler{
+	api.HealthCheckFunction:               {api.NOT_AUTHENTICATED_PRIVILEGE, (*Server).healthCheck},
+	api.VerifyAuthTokenFunction:           {api.CLIENT_PRIVILEGE, (*Server).verifyAuthenticationToken},
+	api.PasswordlessLoginFunction:         {api.NOT_AUTHENTICATED_PRIVILEGE, (*Server).passwordlessLogin},
+	api.PasswordLoginFunction:             {api.NOT_AUTHENTICATED_PRIVILEGE, (*Server).passwordLogin},
+	api.QueryServerStateFunction:          {api.CLIENT_PRIVILEGE, (*Server).queryServerState},
+	api.GetServerOptionsFunction:          {api.CLIENT_PRIVILEGE, (*Server).getServerOptions},
+	api.GetAdvancedGameSettingsFunction:   {api.CLIENT_PRIVILEGE, (*Server).getAdvancedGameSettings},
+	api.ApplyAdvancedGameSettingsFunction: {api.ADMINISTRATOR_PRIVILEGE, (*Server).applyAdvancedGameSettings},
+	api.ClaimServerFunction:               {api.INITIAL_ADMIN_PRIVILEGE, (*Server).claimServer},
+	api.RenameServerFunction:              {api.ADMINISTRATOR_PRIVILEGE, (*Server).renameServer},
+	api.SetClientPasswordFunction:         {api.ADMINISTRATOR_PRIVILEGE, (*Server).setClientPassword},
+	api.SetAdminPasswordFunction:          {api.ADMINISTRATOR_PRIVILEGE, (*Server).setAdminPassword},
+	api.SetAutoLoadSessionNameFunction:    {api.ADMINISTRATOR_PRIVILEGE, (*Server).setAutoLoadSessionName},
+	api.RunCommandFunction:                {api.ADMINISTRATOR_PRIVILEGE, (*Server).runCommand},
+	api.ShutdownFunction:                  {api.ADMINISTRATOR_PRIVILEGE, (*Server).shutdown},
+	api.ApplyServerOptionsFunction:        {api.ADMINISTRATOR_PRIVILEGE, (*Server).applyServerOptions},
+	api.CreateNewGameFunction:             {api.ADMINISTRATOR_PRIVILEGE, (*Server).createNewGame},
+	api.SaveGameFunction:                  {api.ADMINISTRATOR_PRIVILEGE, (*Server).saveGame},
+	api.DeleteSaveFileFunction:            {api.ADMINISTRATOR_PRIVILEGE, (*Server).deleteSaveFile},
+	api.DeleteSaveSessionFunction:         {api.ADMINISTRATOR_PRIVILEGE, (*Server).deleteSaveSession},
+	api.EnumerateSessionsFunction:         {api.ADMINISTRATOR_PRIVILEGE, (*Server).enumerateSessions},
+	api.LoadGameFunction:                  {api.ADMINISTRATOR_PRIVILEGE, (*Server).loadGame},
+	api.UploadSaveGameFunction:            {api.ADMINISTRATOR_PRIVILEGE, (*Server).uploadSaveGame},
+	api.DownloadSaveGameFunction:          {api.ADMINISTRATOR_PRIVILEGE, (*Server).downloadSaveGame},
+}
+
+// functionEnvelope is the {function, data} body every API call is wrapped in.
+type functionEnvelope struct {
+	Function string          `json:"function"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// ServeHTTP implements http.Handler by dispatching the request to the matching API function.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || strings.TrimSuffix(r.URL.Path, "/") != "/api/v1" {
+		http.NotFound(w, r)
+		return
+	}
+
+	call := &functionCall{w: w, r: r}
+
+	envelope, err := s.decodeEnvelope(call)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, api.ErrorCodeInvalidParams, err.Error())
+		return
+	}
+	if envelope.Function == "" {
+		envelope.Function = r.URL.Query().Get("function")
+	}
+	call.data = envelope.Data
+
+	handler, ok := functionHandlers[envelope.Function]
+	if !ok {
+		writeError(w, http.StatusNotFound, api.ErrorCodeUnknownFunction,
+			fmt.Sprintf("unknown API function %q", envelope.Function))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, http.StatusText(s.failureStatus), s.failureStatus)
+		return
+	}
+
+	call.privilege = api.NOT_AUTHENTICATED_PRIVILEGE
+	call.token = strings.TrimSpace(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer"))
+	if call.token != "" {
+		privilege, ok := s.tokens[call.token]
+		if !ok {
+			writeError(w, http.StatusUnauthorized, api.ErrorCodeInvalidToken, "the provided authentication token is not valid")
+			return
+		}
+		call.privilege = privilege
+	}
+
+	if !privilegeSatisfies(call.privilege, handler.privilege) {
+		writeError(w, http.StatusForbidden, api.ErrorCodeInsufficientScope,
+			fmt.Sprintf("%s requires %s privilege, caller has %s", envelope.Function, handler.privilege, call.privilege))
+		return
+	}
+
+	handler.handle(s, call)
+}
+
+// decodeEnvelope reads the function envelope from either a JSON body or,
+// for save uploads, the "data" part of a multipart body.
+func (s *Server) decodeEnvelope(call *functionCall) (*functionEnvelope, error) {
+	var envelope functionEnvelope
+
+	mediaType, params, _ := mime.ParseMediaType(call.r.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		body, err := io.ReadAll(call.r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(body) == 0 {
+			return &envelope, nil
+		}
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, fmt.Errorf("request body is not a valid function envelope: %w", err)
+		}
+		return &envelope, nil
+	}
+
+	if params["boundary"] == "" {
+		return nil, fmt.Errorf("multipart request is missing a boundary")
+	}
+	if err := call.r.ParseMultipartForm(32 << 20); err != nil {
+		return nil, fmt.Errorf("cannot parse multipart body: %w", err)
+	}
+
+	data := call.r.MultipartForm.Value["data"]
+	if len(data) == 0 {
+		return nil, fmt.Errorf("multipart request is missing the data part")
+	}
+	if err := json.Unmarshal([]byte(data[0]), &envelope); err != nil {
+		return nil, fmt.Errorf("multipart data part is not a valid function envelope: %w", err)
+	}
+
+	files := call.r.MultipartForm.File["saveGameFile"]
+	if len(files) > 0 {
+		f, err := files[0].Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		call.saveFile, err = io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &envelope, nil
+}
+
+// decodeData decodes the data object of the call into v, writing a missing_params
+// or invalid_params error and returning false if that is not possible.
+func (call *functionCall) decodeData(v any) bool {
+	if len(call.data) == 0 || string(call.data) == "null" {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeMissingParams, "the data object is required for this function")
+		return false
+	}
+	if err := json.Unmarshal(call.data, v); err != nil {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeInvalidParams, err.Error())
+		return false
+	}
+	return true
+}
+
+// writeData writes a successful response with the given value wrapped in the data envelope.
+func writeData(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]any{"data": v})
+}
+
+// writeNoContent writes a successful response without a body.
+func writeNoContent(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeError writes an API error in the format used by the dedicated server.
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(api.APIError{
+		StatusCode: code,
+		Message:    message,
+	})
+}
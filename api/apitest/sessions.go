@@ -0,0 +1,264 @@
+package apitest
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/alchemicalkube/gofactory/api"
+)
+
+// session is a game session known to the fake server, holding its saves in creation order.
+type session struct {
+	name  string
+	saves []*save
+}
+
+// save is a single save file belonging to a session.
+type save struct {
+	header api.EnumerateSessionsSaveHeader
+	data   []byte
+}
+
+// saveFileMagic is written at the start of save files created by the fake server.
+const saveFileMagic = "GOFACTORY-APITEST-SAVE"
+
+// AddSave stores a save file in the named session, creating the session if it does not exist.
+// An existing save with the same name is replaced.
+func (s *Server) AddSave(sessionName string, saveName string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addSave(sessionName, saveName, slices.Clone(data))
+}
+
+// Save returns the contents of the named save file and whether it exists.
+func (s *Server) Save(saveName string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, sv := s.findSave(saveName)
+	if sv == nil {
+		return nil, false
+	}
+	return slices.Clone(sv.data), true
+}
+
+// ActiveSessionName returns the name of the currently loaded session, or an empty string if no game is running.
+func (s *Server) ActiveSessionName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.activeSessionName
+}
+
+// findSession returns the session with the given name, or nil. Must be called with s.mu held.
+func (s *Server) findSession(name string) *session {
+	for _, sess := range s.sessions {
+		if sess.name == name {
+			return sess
+		}
+	}
+	return nil
+}
+
+// findSave returns the save with the given name and the session it belongs to, or nils.
+// Must be called with s.mu held.
+func (s *Server) findSave(saveName string) (*session, *save) {
+	for _, sess := range s.sessions {
+		for _, sv := range sess.saves {
+			if sv.header.SaveName == saveName {
+				return sess, sv
+			}
+		}
+	}
+	return nil, nil
+}
+
+// addSave stores a save, replacing any existing save with the same name. Must be called with s.mu held.
+func (s *Server) addSave(sessionName string, saveName string, data []byte) {
+	s.removeSave(saveName)
+
+	sess := s.findSession(sessionName)
+	if sess == nil {
+		sess = &session{name: sessionName}
+		s.sessions = append(s.sessions, sess)
+	}
+
+	sess.saves = append(sess.saves, &save{
+		header: api.EnumerateSessionsSaveHeader{
+			SaveVersion:  46,
+			BuildVersion: 385572,
+			SaveName:     saveName,
+			MapName:      "Persistent_Level",
+			MapOptions:   "",
+			SessionName:  sessionName,
+			SaveDateTime: time.Now().UTC().Format("2006.01.02-15.04.05"),
+		},
+		data: data,
+	})
+}
+
+// removeSave deletes a save, reporting whether it existed. Must be called with s.mu held.
+func (s *Server) removeSave(saveName string) bool {
+	sess, sv := s.findSave(saveName)
+	if sv == nil {
+		return false
+	}
+	sess.saves = slices.DeleteFunc(sess.saves, func(other *save) bool { return other == sv })
+	return true
+}
+
+func (s *Server) createNewGame(call *functionCall) {
+	var data api.CreateNewGameData
+	if !call.decodeData(&data) {
+		return
+	}
+	if data.GameData.SessionName == "" {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeMissingParams, "newGameData.sessionName is required")
+		return
+	}
+
+	if s.findSession(data.GameData.SessionName) == nil {
+		s.sessions = append(s.sessions, &session{name: data.GameData.SessionName})
+	}
+	s.activeSessionName = data.GameData.SessionName
+	s.gameRunning = true
+	s.creativeModeEnabled = false
+	s.advancedGameSettings = defaultAdvancedGameSettings()
+
+	writeNoContent(call.w)
+}
+
+func (s *Server) saveGame(call *functionCall) {
+	var data api.SaveGameData
+	if !call.decodeData(&data) {
+		return
+	}
+	if data.SaveName == "" {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeMissingParams, "saveName is required")
+		return
+	}
+	if !s.gameRunning {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeNoActiveSession, "no game session is currently running")
+		return
+	}
+
+	content := fmt.Sprintf("%s\nsession=%s\nsave=%s\n", saveFileMagic, s.activeSessionName, data.SaveName)
+	s.addSave(s.activeSessionName, data.SaveName, []byte(content))
+	writeNoContent(call.w)
+}
+
+func (s *Server) deleteSaveFile(call *functionCall) {
+	var data api.DeleteSaveData
+	if !call.decodeData(&data) {
+		return
+	}
+	if !s.removeSave(data.SaveName) {
+		writeError(call.w, http.StatusNotFound, api.ErrorCodeFileNotFound, fmt.Sprintf("save %q does not exist", data.SaveName))
+		return
+	}
+	writeNoContent(call.w)
+}
+
+func (s *Server) deleteSaveSession(call *functionCall) {
+	var data api.DeleteSaveSessionData
+	if !call.decodeData(&data) {
+		return
+	}
+
+	before := len(s.sessions)
+	s.sessions = slices.DeleteFunc(s.sessions, func(sess *session) bool { return sess.name == data.SessionName })
+	if len(s.sessions) == before {
+		writeError(call.w, http.StatusNotFound, api.ErrorCodeFileNotFound, fmt.Sprintf("session %q does not exist", data.SessionName))
+		return
+	}
+	writeNoContent(call.w)
+}
+
+func (s *Server) enumerateSessions(call *functionCall) {
+	response := api.EnumerateSessionsResponseData{
+		Sessions:            make([]api.EnumerateSessionsResponseDataArray, 0, len(s.sessions)),
+		CurrentSessionIndex: -1,
+	}
+
+	for i, sess := range s.sessions {
+		headers := make([]api.EnumerateSessionsSaveHeader, 0, len(sess.saves))
+		for _, sv := range sess.saves {
+			headers = append(headers, sv.header)
+		}
+		response.Sessions = append(response.Sessions, api.EnumerateSessionsResponseDataArray{
+			SessionName: sess.name,
+			SaveHeaders: headers,
+		})
+		if sess.name == s.activeSessionName {
+			response.CurrentSessionIndex = i
+		}
+	}
+
+	writeData(call.w, response)
+}
+
+func (s *Server) loadGame(call *functionCall) {
+	var data api.LoadGameRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+
+	sess, sv := s.findSave(data.SaveName)
+	if sv == nil {
+		writeError(call.w, http.StatusNotFound, api.ErrorCodeFileNotFound, fmt.Sprintf("save %q does not exist", data.SaveName))
+		return
+	}
+
+	s.activeSessionName = sess.name
+	s.gameRunning = true
+	writeNoContent(call.w)
+}
+
+func (s *Server) uploadSaveGame(call *functionCall) {
+	var data api.UploadSaveGameDataRequest
+	if !call.decodeData(&data) {
+		return
+	}
+	if data.SaveName == "" {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeMissingParams, "saveName is required")
+		return
+	}
+	if len(call.saveFile) == 0 {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeInvalidSaveGame, "the saveGameFile part is missing or empty")
+		return
+	}
+
+	// The dedicated server reads the session name from the save header. The fake server
+	// keeps the session of a save it already knows, and otherwise uses the save name.
+	sessionName := data.SaveName
+	if sess, _ := s.findSave(data.SaveName); sess != nil {
+		sessionName = sess.name
+	}
+	s.addSave(sessionName, data.SaveName, call.saveFile)
+
+	if data.LoadImmediately {
+		s.activeSessionName = sessionName
+		s.gameRunning = true
+	}
+	writeNoContent(call.w)
+}
+
+func (s *Server) downloadSaveGame(call *functionCall) {
+	var data api.DownloadSaveGameRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+
+	_, sv := s.findSave(data.SaveName)
+	if sv == nil {
+		writeError(call.w, http.StatusNotFound, api.ErrorCodeFileNotFound, fmt.Sprintf("save %q does not exist", data.SaveName))
+		return
+	}
+
+	call.w.Header().Set("Content-Type", "application/octet-stream")
+	call.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", data.SaveName+".sav"))
+	call.w.Header().Set("Content-Length", strconv.Itoa(len(sv.data)))
+	call.w.WriteHeader(http.StatusOK)
+	_, _ = call.w.Write(sv.data)
+}
@@ -0,0 +1,239 @@
+package apitest
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+
+	"github.com/alchemicalkube/gofactory/api"
+)
+
+// restartRequiredOptions are the server options that only take effect after the server restarts.
+// Until then, applied values are reported as pending.
+var restartRequiredOptions = map[string]bool{
+	"FG.NetworkQuality": true,
+}
+
+// defaultServerOptions returns the server options of a freshly installed dedicated server.
+func defaultServerOptions() map[string]string {
+	return map[string]string{
+		"FG.DSAutoPause":            "True",
+		"FG.DSAutoSaveOnDisconnect": "True",
+		"FG.DisableSeasonalEvents":  "False",
+		"FG.AutosaveInterval":       "300",
+		"FG.ServerRestartTimeSlot":  "1440",
+		"FG.SendGameplayData":       "True",
+		"FG.NetworkQuality":         "3",
+	}
+}
+
+// defaultAdvancedGameSettings returns the advanced game settings of a new game.
+func defaultAdvancedGameSettings() map[string]string {
+	return map[string]string{
+		"FG.GameRules.NoPower":                         "False",
+		"FG.GameRules.StartingTier":                    "0",
+		"FG.GameRules.DisableArachnidCreatures":        "False",
+		"FG.GameRules.NoUnlockCost":                    "False",
+		"FG.GameRules.SetGamePhase":                    "0",
+		"FG.GameRules.UnlockAllResearchSchematics":     "False",
+		"FG.GameRules.UnlockInstantAltRecipes":         "False",
+		"FG.GameRules.UnlockAllResourceSinkSchematics": "False",
+		"FG.PlayerRules.NoBuildCost":                   "False",
+		"FG.PlayerRules.GodMode":                       "False",
+		"FG.PlayerRules.FlightMode":                    "False",
+	}
+}
+
+// ServerOptions returns a copy of the currently applied server options, keyed by their FG.* name.
+func (s *Server) ServerOptions() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.serverOptions)
+}
+
+// PendingServerOptions returns a copy of the server options waiting for a restart to be applied.
+func (s *Server) PendingServerOptions() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.pendingServerOptions)
+}
+
+// AdvancedGameSettings returns a copy of the currently applied advanced game settings.
+func (s *Server) AdvancedGameSettings() map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.advancedGameSettings)
+}
+
+// AutoLoadSessionName returns the session the fake server would load on startup.
+func (s *Server) AutoLoadSessionName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.autoLoadSessionName
+}
+
+func (s *Server) healthCheck(call *functionCall) {
+	writeData(call.w, api.HealthCheckResponse{
+		Health:     s.health,
+		CustomData: s.serverCustomData,
+	})
+}
+
+func (s *Server) queryServerState(call *functionCall) {
+	state := api.QueryServerStateData{
+		ActiveSessionName:   s.activeSessionName,
+		PlayerLimit:         4,
+		ActiveSchematic:     "None",
+		GamePhase:           "None",
+		IsGameRunning:       s.gameRunning,
+		AverageTickRate:     30,
+		AutoLoadSessionName: s.autoLoadSessionName,
+	}
+	if s.gameRunning {
+		state.GamePhase = "/Script/FactoryGame.FGGamePhase'/Game/FactoryGame/GamePhases/GP_Project_Assembly_Phase_0.GP_Project_Assembly_Phase_0'"
+	}
+
+	writeData(call.w, map[string]any{"serverGameState": state})
+}
+
+func (s *Server) getServerOptions(call *functionCall) {
+	writeData(call.w, map[string]any{
+		"serverOptions":        s.serverOptions,
+		"pendingServerOptions": s.pendingServerOptions,
+	})
+}
+
+func (s *Server) applyServerOptions(call *functionCall) {
+	var data struct {
+		UpdatedServerOptions map[string]string `json:"updatedServerOptions"`
+	}
+	if !call.decodeData(&data) {
+		return
+	}
+
+	for key, value := range data.UpdatedServerOptions {
+		if restartRequiredOptions[key] && s.serverOptions[key] != value {
+			s.pendingServerOptions[key] = value
+			continue
+		}
+		s.serverOptions[key] = value
+		delete(s.pendingServerOptions, key)
+	}
+	writeNoContent(call.w)
+}
+
+func (s *Server) getAdvancedGameSettings(call *functionCall) {
+	writeData(call.w, map[string]any{
+		"creativeModeEnabled":  s.creativeModeEnabled,
+		"advancedGameSettings": s.advancedGameSettings,
+	})
+}
+
+func (s *Server) applyAdvancedGameSettings(call *functionCall) {
+	var data struct {
+		AppliedAdvancedGameSettings map[string]string `json:"appliedAdvancedGameSettings"`
+	}
+	if !call.decodeData(&data) {
+		return
+	}
+	if !s.gameRunning {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeNoActiveSession, "no game session is currently running")
+		return
+	}
+
+	maps.Copy(s.advancedGameSettings, data.AppliedAdvancedGameSettings)
+	s.creativeModeEnabled = true
+	writeNoContent(call.w)
+}
+
+func (s *Server) renameServer(call *functionCall) {
+	var data api.RenameRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+	if data.ServerName == "" {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeMissingParams, "serverName is required")
+		return
+	}
+
+	s.serverName = data.ServerName
+	writeNoContent(call.w)
+}
+
+func (s *Server) setAutoLoadSessionName(call *functionCall) {
+	var data api.SetAutoLoadSessionRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+
+	s.autoLoadSessionName = data.SessionName
+	writeNoContent(call.w)
+}
+
+func (s *Server) shutdown(call *functionCall) {
+	s.shutdowns++
+
+	// The dedicated server is expected to be restarted by its service manager,
+	// which applies pending options and loads the auto-load session.
+	maps.Copy(s.serverOptions, s.pendingServerOptions)
+	clear(s.pendingServerOptions)
+	s.gameRunning = false
+	s.activeSessionName = ""
+	if s.autoLoadSessionName != "" && s.findSession(s.autoLoadSessionName) != nil {
+		s.activeSessionName = s.autoLoadSessionName
+		s.gameRunning = true
+	}
+
+	writeNoContent(call.w)
+}
+
+// registerDefaultCommands registers the console commands the fake server understands out of the box.
+func (s *Server) registerDefaultCommands() {
+	s.commands["server.generateapitoken"] = func(string) string {
+		return "New API Token: " + s.issueToken(api.API_TOKEN_PRIVILEGE)
+	}
+	s.commands["server.invalidateallapitokens"] = func(string) string {
+		s.revokeTokens(api.API_TOKEN_PRIVILEGE)
+		return "All API Tokens have been invalidated"
+	}
+	s.commands["server.savegame"] = func(command string) string {
+		fields := strings.Fields(command)
+		if len(fields) < 2 {
+			return "Usage: server.SaveGame <SaveName>"
+		}
+		if !s.gameRunning {
+			return "Cannot save, no game session is currently running"
+		}
+		content := fmt.Sprintf("%s\nsession=%s\nsave=%s\n", saveFileMagic, s.activeSessionName, fields[1])
+		s.addSave(s.activeSessionName, fields[1], []byte(content))
+		return "Game saved as " + fields[1]
+	}
+	s.commands["server.querystate"] = func(string) string {
+		return fmt.Sprintf("Active Session: %s\nGame Running: %t\nAuto Load Session: %s\nPlayer Limit: 4",
+			s.activeSessionName, s.gameRunning, s.autoLoadSessionName)
+	}
+}
+
+func (s *Server) runCommand(call *functionCall) {
+	var data api.RunCommandRequestData
+	if !call.decodeData(&data) {
+		return
+	}
+	if strings.TrimSpace(data.Command) == "" {
+		writeError(call.w, http.StatusBadRequest, api.ErrorCodeMissingParams, "command is required")
+		return
+	}
+
+	name := strings.ToLower(strings.Fields(data.Command)[0])
+
+	result := ""
+	if handler, ok := s.commands[name]; ok {
+		result = handler(data.Command)
+	}
+
+	writeData(call.w, map[string]any{
+		"commandResult": result,
+		"returnValue":   true,
+	})
+}
@@ -0,0 +1,346 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BootstrapStep names a step of Bootstrap.
+type BootstrapStep string
+
+// Steps of Bootstrap, in the order they run.
+const (
+	BootstrapClaimStep          BootstrapStep = "claim server"
+	BootstrapClientPasswordStep BootstrapStep = "set client password"
+	BootstrapServerOptionsStep  BootstrapStep = "apply server options"
+	BootstrapGameStep           BootstrapStep = "create or upload game"
+	BootstrapAutoLoadStep       BootstrapStep = "set auto-load session"
+)
+
+// BootstrapConfig describes the desired state of a freshly installed server for Bootstrap.
+// Only ServerName and AdminPassword are required, the steps of the other fields are skipped when they are not set.
+type BootstrapConfig struct {
+	// ServerName is the name to claim the server with.
+	ServerName string
+
+	// AdminPassword is the administrator password to claim the server with, or to log in with if it
+	// is already claimed.
+	AdminPassword string
+
+	// ClientPassword is the client protection password to set.
+	ClientPassword string
+
+	// ServerOptions are the server options to apply. Options that already have the desired value,
+	// including those pending a restart, are not sent again.
+	ServerOptions ServerOptions
+
+	// NewGame is the game to create. It is skipped if a session with its name already exists.
+	NewGame *CreateNewGameRequestData
+
+	// SaveGame is the save to upload instead of creating a new game.
+	SaveGame *BootstrapSaveGame
+
+	// AutoLoadSessionName is the session to load when the server starts. It defaults to the session
+	// of NewGame or SaveGame.
+	AutoLoadSessionName string
+
+	// OnStep, if set, is called after every step with its outcome.
+	OnStep func(result BootstrapStepResult)
+}
+
+// BootstrapSaveGame is a save game uploaded by Bootstrap.
+type BootstrapSaveGame struct {
+	// File is the content of the save file.
+	File io.Reader
+
+	// Filename is the name of the save file sent to the server, such as "Factory.sav".
+	Filename string
+
+	// Settings holds the save name and whether to load it once uploaded. The upload is skipped if
+	// a save with this name already exists.
+	Settings UploadSaveGameDataRequest
+}
+
+// BootstrapStepResult is the outcome of a step of Bootstrap.
+type BootstrapStepResult struct {
+	// Step is the step that ran.
+	Step BootstrapStep
+
+	// Skipped is true when the step was already done, or not requested by the BootstrapConfig.
+	Skipped bool
+
+	// Reason explains why the step was skipped.
+	Reason string
+}
+
+// BootstrapResult is the outcome of Bootstrap.
+type BootstrapResult struct {
+	// AuthenticationToken is the Administrator token the client holds once the server is bootstrapped.
+	AuthenticationToken string
+
+	// Steps holds the outcome of every step that ran, in order.
+	Steps []BootstrapStepResult
+}
+
+// Validate checks that the configuration can be bootstrapped before anything is sent to the server.
+func (b BootstrapConfig) Validate() error {
+	var errs []error
+	if len(b.ServerName) == 0 {
+		errs = append(errs, errors.New("server name is required"))
+	}
+	if len(b.AdminPassword) == 0 {
+		errs = append(errs, errors.New("admin password is required"))
+	}
+	if len(b.ClientPassword) != 0 && b.ClientPassword == b.AdminPassword {
+		errs = append(errs, errors.New("client password cannot be the admin password"))
+	}
+	if b.NewGame != nil && b.SaveGame != nil {
+		errs = append(errs, errors.New("a new game and a save game cannot both be set"))
+	}
+	if b.NewGame != nil && len(b.NewGame.SessionName) == 0 {
+		errs = append(errs, errors.New("new game session name is required"))
+	}
+	if b.SaveGame != nil && (b.SaveGame.File == nil || len(b.SaveGame.Settings.SaveName) == 0) {
+		errs = append(errs, errors.New("save game file and save name are required"))
+	}
+	if err := b.ServerOptions.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Bootstrap brings a freshly installed server to the state described by config: it claims the server, sets the
+// client password, applies the server options, creates a new game or uploads a save, and sets the auto-load session.
+//
+// Every step first checks whether it is already done, so Bootstrap can be run again after a failure, or against a
+// server it already bootstrapped, and only makes the changes still needed. A claimed server is logged in to with the
+// admin password. The client holds the Administrator token afterwards, which is also returned in the result.
+//
+// On failure, the result holds the steps that ran before the failing one, and the error names that step.
+func (c *GoFactoryClient) Bootstrap(ctx context.Context, config BootstrapConfig) (*BootstrapResult, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid bootstrap config: %w", err)
+	}
+
+	result := &BootstrapResult{}
+	steps := []struct {
+		step BootstrapStep
+
+		// run returns why the step was skipped, or an empty string if it made its change.
+		run func(ctx context.Context, config *BootstrapConfig) (string, error)
+	}{
+		{BootstrapClaimStep, c.bootstrapClaim},
+		{BootstrapClientPasswordStep, c.bootstrapClientPassword},
+		{BootstrapServerOptionsStep, c.bootstrapServerOptions},
+		{BootstrapGameStep, c.bootstrapGame},
+		{BootstrapAutoLoadStep, c.bootstrapAutoLoad},
+	}
+
+	for _, s := range steps {
+		reason, err := s.run(ctx, &config)
+		if err != nil {
+			return result, fmt.Errorf("cannot %s: %w", s.step, err)
+		}
+
+		stepResult := BootstrapStepResult{Step: s.step, Skipped: len(reason) != 0, Reason: reason}
+		result.Steps = append(result.Steps, stepResult)
+		if config.OnStep != nil {
+			config.OnStep(stepResult)
+		}
+	}
+
+	result.AuthenticationToken = c.Token()
+	return result, nil
+}
+
+// bootstrapClaim claims the server, or logs in with the admin password if it is already claimed.
+func (c *GoFactoryClient) bootstrapClaim(ctx context.Context, config *BootstrapConfig) (string, error) {
+	err := c.PasswordlessLogin(ctx, INITIAL_ADMIN_PRIVILEGE)
+	if err == nil {
+		return "", c.ClaimServer(ctx, ClaimRequestData{ServerName: config.ServerName, AdminPassword: config.AdminPassword})
+	}
+	if !errors.Is(err, ErrPasswordlessLoginNotPossible) && !errors.Is(err, ErrServerClaimed) {
+		return "", err
+	}
+
+	if err := c.PasswordLogin(ctx, ADMINISTRATOR_PRIVILEGE, config.AdminPassword); err != nil {
+		return "", fmt.Errorf("the server is already claimed, and logging in with the admin password failed: %w", err)
+	}
+	return "the server is already claimed", nil
+}
+
+// bootstrapClientPassword sets the client password, unless logging in with it already grants the Client privilege.
+func (c *GoFactoryClient) bootstrapClientPassword(ctx context.Context, config *BootstrapConfig) (string, error) {
+	if len(config.ClientPassword) == 0 {
+		return "no client password requested", nil
+	}
+
+	probe := c.WithToken("")
+	err := probe.PasswordLogin(ctx, CLIENT_PRIVILEGE, config.ClientPassword)
+	switch {
+	case err == nil && probe.Privilege() == CLIENT_PRIVILEGE:
+		return "the client password is already set", nil
+	case err != nil && !errors.Is(err, ErrWrongPassword):
+		return "", err
+	}
+
+	return "", c.SetClientPassword(ctx, config.ClientPassword)
+}
+
+// bootstrapServerOptions applies the server options that do not have their desired value yet.
+func (c *GoFactoryClient) bootstrapServerOptions(ctx context.Context, config *BootstrapConfig) (string, error) {
+	desired, err := optionValues(config.ServerOptions)
+	if err != nil {
+		return "", err
+	}
+	if len(desired) == 0 {
+		return "no server options requested", nil
+	}
+
+	options, err := c.GetServerOptions(ctx)
+	if err != nil {
+		return "", err
+	}
+	current, err := optionValues(options.ServerOptions)
+	if err != nil {
+		return "", err
+	}
+	pending, err := optionValues(options.PendingServerOptions)
+	if err != nil {
+		return "", err
+	}
+	for key, value := range pending {
+		current[key] = value
+	}
+
+	changed := make(map[string]string)
+	for key, value := range desired {
+		if current[key] != value {
+			changed[key] = value
+		}
+	}
+	if len(changed) == 0 {
+		return "the server options are already set", nil
+	}
+
+	data, err := json.Marshal(changed)
+	if err != nil {
+		return "", err
+	}
+	var update ServerOptions
+	if err := json.Unmarshal(data, &update); err != nil {
+		return "", err
+	}
+	return "", c.ApplyServerOptions(ctx, update)
+}
+
+// bootstrapGame creates the new game or uploads the save game, unless its session or save already exists,
+// and checks that the server runs the new session or lists the uploaded save afterwards. It defaults the
+// auto-load session name to the session of the game.
+func (c *GoFactoryClient) bootstrapGame(ctx context.Context, config *BootstrapConfig) (string, error) {
+	if config.NewGame == nil && config.SaveGame == nil {
+		return "no game requested", nil
+	}
+
+	sessions, err := c.EnumerateSessions(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if config.NewGame != nil {
+		if len(config.AutoLoadSessionName) == 0 {
+			config.AutoLoadSessionName = config.NewGame.SessionName
+		}
+		for _, session := range sessions.Sessions {
+			if session.SessionName == config.NewGame.SessionName {
+				return "the session already exists", nil
+			}
+		}
+		if err := c.CreateNewGame(ctx, *config.NewGame); err != nil {
+			return "", err
+		}
+
+		// The server loads the new session right away, before it has a save for EnumerateSessions to list.
+		state, err := c.QueryServerState(ctx)
+		if err != nil {
+			return "", err
+		}
+		if state.ActiveSessionName != config.NewGame.SessionName {
+			return "", fmt.Errorf("the server runs session %q instead of %s after creating it",
+				state.ActiveSessionName, config.NewGame.SessionName)
+		}
+		return "", nil
+	}
+
+	save := config.SaveGame
+	reason := "the save already exists"
+	sessionName := findSaveSession(sessions, save.Settings.SaveName)
+	if len(sessionName) == 0 {
+		reason = ""
+		if err := c.UploadSaveGame(ctx, save.File, save.Filename, save.Settings); err != nil {
+			return "", err
+		}
+
+		// The session name is read by the server from the save header.
+		sessions, err = c.EnumerateSessions(ctx)
+		if err != nil {
+			return "", err
+		}
+		sessionName = findSaveSession(sessions, save.Settings.SaveName)
+		if len(sessionName) == 0 {
+			return "", fmt.Errorf("the server does not list save %s after uploading it", save.Settings.SaveName)
+		}
+	}
+
+	if len(config.AutoLoadSessionName) == 0 {
+		config.AutoLoadSessionName = sessionName
+	}
+	return reason, nil
+}
+
+// bootstrapAutoLoad sets the auto-load session name, unless it is already set.
+func (c *GoFactoryClient) bootstrapAutoLoad(ctx context.Context, config *BootstrapConfig) (string, error) {
+	if len(config.AutoLoadSessionName) == 0 {
+		return "no auto-load session requested", nil
+	}
+
+	state, err := c.QueryServerState(ctx)
+	if err != nil {
+		return "", err
+	}
+	if state.AutoLoadSessionName == config.AutoLoadSessionName {
+		return "the auto-load session is already set", nil
+	}
+
+	_, err = c.SetAutoLoadSessionName(ctx, config.AutoLoadSessionName)
+	return "", err
+}
+
+// findSaveSession returns the name of the session holding the save, or an empty string if there is none.
+func findSaveSession(sessions *EnumerateSessionsResponseData, saveName string) string {
+	for _, session := range sessions.Sessions {
+		for _, header := range session.SaveHeaders {
+			if header.SaveName == saveName {
+				return session.SessionName
+			}
+		}
+	}
+	return ""
+}
+
+// optionValues returns the options that are set, keyed by their FG.* wire keys.
+func optionValues(options ServerOptions) (map[string]string, error) {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
@@ -0,0 +1,199 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+// dropCall is an interceptor that pretends calls to function succeeded without sending them,
+// like a server that accepts a change and loses it.
+func dropCall(function string) api.Interceptor {
+	return func(ctx context.Context, call *api.Call, next api.Invoker) error {
+		if call.Function == function {
+			return nil
+		}
+		return next(ctx, call)
+	}
+}
+
+// failOnce is an interceptor that fails the first call to function without sending it.
+func failOnce(function string) api.Interceptor {
+	failed := false
+	return func(ctx context.Context, call *api.Call, next api.Invoker) error {
+		if call.Function == function && !failed {
+			failed = true
+			return errors.New("injected failure")
+		}
+		return next(ctx, call)
+	}
+}
+
+func TestBootstrapResume(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func() api.BootstrapConfig
+
+		// failing is the function that fails once during the first run, and failedStep the step it fails.
+		failing    string
+		failedStep api.BootstrapStep
+
+		// wantSkipped lists, for each step of the second run, whether it was skipped.
+		wantSkipped  []bool
+		wantAutoLoad string
+	}{
+		{
+			name: "complete run",
+			config: func() api.BootstrapConfig {
+				return api.BootstrapConfig{
+					ClientPassword: "client",
+					ServerOptions:  api.ServerOptions{AutosaveInterval: "600"},
+					NewGame:        &api.CreateNewGameRequestData{SessionName: "Fresh"},
+				}
+			},
+			wantSkipped:  []bool{true, true, true, true, true},
+			wantAutoLoad: "Fresh",
+		},
+		{
+			name: "server options failed",
+			config: func() api.BootstrapConfig {
+				return api.BootstrapConfig{
+					ClientPassword: "client",
+					ServerOptions:  api.ServerOptions{AutosaveInterval: "600"},
+					NewGame:        &api.CreateNewGameRequestData{SessionName: "Fresh"},
+				}
+			},
+			failing:      api.ApplyServerOptionsFunction,
+			failedStep:   api.BootstrapServerOptionsStep,
+			wantSkipped:  []bool{true, true, false, false, false},
+			wantAutoLoad: "Fresh",
+		},
+		{
+			name: "auto-load failed after upload",
+			config: func() api.BootstrapConfig {
+				return api.BootstrapConfig{
+					SaveGame: &api.BootstrapSaveGame{
+						File:     bytes.NewReader([]byte("save")),
+						Filename: "Uploaded.sav",
+						Settings: api.UploadSaveGameDataRequest{SaveName: "Uploaded"},
+					},
+				}
+			},
+			failing:      api.SetAutoLoadSessionNameFunction,
+			failedStep:   api.BootstrapAutoLoadStep,
+			wantSkipped:  []bool{true, true, true, true, false},
+			wantAutoLoad: "Uploaded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			ctx := context.Background()
+
+			config := tt.config()
+			config.ServerName = "Test"
+			config.AdminPassword = "admin"
+
+			var opts []api.Option
+			if len(tt.failing) != 0 {
+				opts = append(opts, api.WithInterceptors(failOnce(tt.failing)))
+			}
+			_, err := server.NewClient("", opts...).Bootstrap(ctx, config)
+			switch {
+			case len(tt.failing) == 0 && err != nil:
+				t.Fatalf("first Bootstrap() error = %v", err)
+			case len(tt.failing) != 0 && (err == nil || !strings.Contains(err.Error(), string(tt.failedStep))):
+				t.Fatalf("first Bootstrap() error = %v, want it to name the %q step", err, tt.failedStep)
+			}
+
+			// The second run starts without a token, as a new process would.
+			client := server.NewClient("")
+			result, err := client.Bootstrap(ctx, config)
+			if err != nil {
+				t.Fatalf("second Bootstrap() error = %v", err)
+			}
+
+			var skipped []bool
+			for _, step := range result.Steps {
+				skipped = append(skipped, step.Skipped)
+				if step.Skipped && len(step.Reason) == 0 {
+					t.Errorf("step %q was skipped without a reason", step.Step)
+				}
+			}
+			if !slices.Equal(skipped, tt.wantSkipped) {
+				t.Errorf("second run skipped %v, want %v", skipped, tt.wantSkipped)
+			}
+
+			if claims, err := api.ParseToken(result.AuthenticationToken); err != nil || claims.Privilege != api.ADMINISTRATOR_PRIVILEGE {
+				t.Errorf("result token privilege = %v (%v), want %s", claims, err, api.ADMINISTRATOR_PRIVILEGE)
+			}
+			if server.ServerName() != "Test" || server.AutoLoadSessionName() != tt.wantAutoLoad {
+				t.Errorf("server %q auto-loads %q, want Test auto-loading %q",
+					server.ServerName(), server.AutoLoadSessionName(), tt.wantAutoLoad)
+			}
+		})
+	}
+}
+
+func TestBootstrapGameChecksResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  api.BootstrapConfig
+		dropped string
+		wantErr string
+	}{
+		{
+			name: "new game not running",
+			config: api.BootstrapConfig{
+				NewGame: &api.CreateNewGameRequestData{SessionName: "Fresh"},
+			},
+			dropped: api.CreateNewGameFunction,
+			wantErr: "instead of Fresh",
+		},
+		{
+			name: "uploaded save not listed",
+			config: api.BootstrapConfig{
+				SaveGame: &api.BootstrapSaveGame{
+					File:     bytes.NewReader([]byte("save")),
+					Filename: "Uploaded.sav",
+					Settings: api.UploadSaveGameDataRequest{SaveName: "Uploaded"},
+				},
+			},
+			dropped: api.UploadSaveGameFunction,
+			wantErr: "does not list save Uploaded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			client := server.NewClient("", api.WithInterceptors(dropCall(tt.dropped)))
+
+			config := tt.config
+			config.ServerName = "Test"
+			config.AdminPassword = "admin"
+			result, err := client.Bootstrap(context.Background(), config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Bootstrap() error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), string(api.BootstrapGameStep)) {
+				t.Errorf("Bootstrap() error = %v, want it to name the %q step", err, api.BootstrapGameStep)
+			}
+			if got := len(result.Steps); got != 3 {
+				t.Errorf("steps run = %d, want 3 before the game step", got)
+			}
+			if server.AutoLoadSessionName() != "" {
+				t.Errorf("auto-load session = %q, want it left unset", server.AutoLoadSessionName())
+			}
+		})
+	}
+}
@@ -0,0 +1,463 @@
+// Package cassette records HTTPS API exchanges with a dedicated server into cassette files, and replays
+// them to a GoFactoryClient offline, so tooling can be tested against payloads captured from a real server.
+//
+// Record once against a live server:
+//
+//	recorder, err := cassette.New("testdata/sessions.json", cassette.ModeRecord)
+//	client := api.NewClient(url, api.WithAuthToken(token), api.WithMiddleware(recorder.Middleware()))
+//	sessions, err := client.EnumerateSessions(ctx)
+//	err = recorder.Save()
+//
+// Then replay in tests, without a server:
+//
+//	recorder, err := cassette.New("testdata/sessions.json", cassette.ModeReplay)
+//	client := api.NewClient("https://recorded", api.WithMiddleware(recorder.Middleware()))
+//
+// Tokens and passwords are redacted before anything is written to disk. Response bodies that are not JSON
+// are only recorded up to MaxBinaryBodySize, so recording a large save game download does not hold it in memory.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/alchemicalkube/gofactory/api"
+)
+
+// Redacted replaces tokens and passwords in recorded requests and responses.
+const Redacted = "REDACTED"
+
+// MaxBinaryBodySize is the size above which a response body that is not JSON, such as a downloaded save game,
+// is not recorded. Such a body is passed through to the client without being held in memory.
+const MaxBinaryBodySize = 1 << 20
+
+// ErrInteractionNotFound is returned in replay mode when the cassette holds no response for a request.
+var ErrInteractionNotFound = errors.New("cassette error: no recorded interaction matches the request")
+
+// ErrBodyNotRecorded is returned in replay mode for a response whose body was larger than MaxBinaryBodySize.
+var ErrBodyNotRecorded = errors.New("cassette error: the response body was too large to record")
+
+// Mode selects whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette file and never contacts the server.
+	ModeReplay Mode = iota
+
+	// ModeRecord sends requests to the server and records every exchange until Save is called.
+	ModeRecord
+)
+
+// redactedKeys are the JSON keys, compared in lowercase, whose values are always redacted.
+var redactedKeys = map[string]bool{
+	"password":            true,
+	"adminpassword":       true,
+	"clientpassword":      true,
+	"authenticationtoken": true,
+	"token":               true,
+}
+
+// tokenPattern matches dedicated server authentication tokens, a base64 payload followed by a hex signature,
+// wherever they appear in a string, such as the result of the server.GenerateAPIToken console command.
+var tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_-]{16,}={0,2}\.[0-9A-Fa-f]{32,}`)
+
+// Cassette is the content of a cassette file.
+type Cassette struct {
+	// Interactions are the recorded exchanges, in the order they happened.
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	// Request is the recorded request.
+	Request Request `json:"request"`
+
+	// Response is the recorded response.
+	Response Response `json:"response"`
+}
+
+// Request is a recorded API request.
+type Request struct {
+	// Function is the API function that was called.
+	Function string `json:"function"`
+
+	// Headers are the request headers, with Authorization redacted.
+	Headers http.Header `json:"headers,omitempty"`
+
+	// Body is the redacted JSON request body. It is empty for multipart uploads.
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// Response is a recorded API response.
+type Response struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int `json:"statusCode"`
+
+	// Headers are the response headers.
+	Headers http.Header `json:"headers,omitempty"`
+
+	// Body is the redacted response body, if it is JSON.
+	Body json.RawMessage `json:"body,omitempty"`
+
+	// BodyBase64 is the base64 encoded response body, if it is not JSON, such as a downloaded save game.
+	BodyBase64 string `json:"bodyBase64,omitempty"`
+
+	// BodyOmitted is set when the body was not JSON and larger than MaxBinaryBodySize, so it was not recorded.
+	BodyOmitted bool `json:"bodyOmitted,omitempty"`
+}
+
+// Load reads a cassette file.
+func Load(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read cassette: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("cannot decode cassette %s: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to path, creating its directory if needed.
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("cannot create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cannot write cassette: %w", err)
+	}
+	return nil
+}
+
+// Recorder is an http.RoundTripper that records exchanges into a cassette or replays them from one.
+// It is safe for concurrent use.
+type Recorder struct {
+	path     string
+	mode     Mode
+	next     http.RoundTripper
+	mu       sync.Mutex
+	cassette *Cassette
+
+	// used marks the interactions already replayed.
+	used []bool
+}
+
+// New creates a Recorder for the cassette file at path. In replay mode the file is loaded immediately.
+// In record mode the file is only written by Save, replacing any previous recording.
+func New(path string, mode Mode) (*Recorder, error) {
+	recorder := &Recorder{
+		path:     path,
+		mode:     mode,
+		next:     http.DefaultTransport,
+		cassette: &Cassette{},
+	}
+
+	if mode == ModeReplay {
+		cassette, err := Load(path)
+		if err != nil {
+			return nil, err
+		}
+		recorder.cassette = cassette
+		recorder.used = make([]bool, len(cassette.Interactions))
+	}
+
+	return recorder, nil
+}
+
+// Middleware returns an api.Middleware that passes the client's requests through the recorder.
+// In record mode, requests are sent with the client's own transport, so its TLS options still apply.
+func (r *Recorder) Middleware() api.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &middleware{recorder: r, next: next}
+	}
+}
+
+// middleware is the http.RoundTripper returned by Recorder.Middleware.
+type middleware struct {
+	recorder *Recorder
+	next     http.RoundTripper
+}
+
+func (m *middleware) RoundTrip(request *http.Request) (*http.Response, error) {
+	return m.recorder.roundTrip(request, m.next)
+}
+
+// RoundTrip implements http.RoundTripper. In record mode requests are sent with http.DefaultTransport.
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	return r.roundTrip(request, r.next)
+}
+
+// Cassette returns the interactions recorded or loaded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return &Cassette{Interactions: append([]Interaction(nil), r.cassette.Interactions...)}
+}
+
+// Save writes the recorded interactions to the cassette file. It does nothing in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	return r.Cassette().Save(r.path)
+}
+
+// roundTrip records or replays a single request.
+func (r *Recorder) roundTrip(request *http.Request, next http.RoundTripper) (*http.Response, error) {
+	recorded, err := recordRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeReplay {
+		return r.replay(request, recorded)
+	}
+
+	response, err := next.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, complete, err := readBody(response)
+	if err != nil {
+		return nil, err
+	}
+
+	recordedResponse := recordResponse(response, body)
+	if !complete {
+		recordedResponse.BodyOmitted = true
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  recorded,
+		Response: recordedResponse,
+	})
+	r.mu.Unlock()
+
+	return response, nil
+}
+
+// readBody reads the body of a response to record it, and replaces it with one the client can still read.
+// A body that is not JSON is read up to MaxBinaryBodySize, and complete is false if it is larger, in which
+// case the rest of it is left to the client.
+func readBody(response *http.Response) (body []byte, complete bool, err error) {
+	if isJSON(response.Header.Get("Content-Type")) {
+		body, err = io.ReadAll(response.Body)
+		_ = response.Body.Close()
+		if err != nil {
+			return nil, false, err
+		}
+		response.Body = io.NopCloser(bytes.NewReader(body))
+		return body, true, nil
+	}
+
+	body, err = io.ReadAll(io.LimitReader(response.Body, MaxBinaryBodySize+1))
+	if err != nil {
+		_ = response.Body.Close()
+		return nil, false, err
+	}
+	if len(body) <= MaxBinaryBodySize {
+		_ = response.Body.Close()
+		response.Body = io.NopCloser(bytes.NewReader(body))
+		return body, true, nil
+	}
+
+	response.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), response.Body), response.Body}
+	return nil, false, nil
+}
+
+// replay returns the first unused interaction matching the request, or the last matching
+// interaction if all of them were already used, so polling calls can be replayed any number of times.
+func (r *Recorder) replay(request *http.Request, recorded Request) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !interaction.Request.matches(recorded) {
+			continue
+		}
+		found = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, recorded.Function, recorded.Body)
+	}
+	r.used[found] = true
+
+	return r.cassette.Interactions[found].Response.httpResponse(request)
+}
+
+// matches reports whether two recorded requests call the same function with the same body.
+func (r Request) matches(other Request) bool {
+	return r.Function == other.Function && bytes.Equal(compactJSON(r.Body), compactJSON(other.Body))
+}
+
+// recordRequest captures the redacted function, headers and JSON body of a request.
+func recordRequest(request *http.Request) (Request, error) {
+	recorded := Request{
+		Function: request.URL.Query().Get("function"),
+		Headers:  request.Header.Clone(),
+	}
+	if recorded.Headers.Get("Authorization") != "" {
+		recorded.Headers.Set("Authorization", "Bearer "+Redacted)
+	}
+
+	if !isJSON(request.Header.Get("Content-Type")) || request.Body == nil || request.GetBody == nil {
+		return recorded, nil
+	}
+
+	body, err := request.GetBody()
+	if err != nil {
+		return recorded, fmt.Errorf("cannot read request body: %w", err)
+	}
+	data, err := io.ReadAll(body)
+	_ = body.Close()
+	if err != nil {
+		return recorded, fmt.Errorf("cannot read request body: %w", err)
+	}
+	recorded.Body = RedactJSON(data)
+
+	return recorded, nil
+}
+
+// recordResponse captures the status, headers and redacted body of a response.
+func recordResponse(response *http.Response, body []byte) Response {
+	recorded := Response{
+		StatusCode: response.StatusCode,
+		Headers:    response.Header.Clone(),
+	}
+
+	if json.Valid(body) {
+		recorded.Body = RedactJSON(body)
+	} else if utf8.Valid(body) && isJSON(response.Header.Get("Content-Type")) {
+		recorded.Body, _ = json.Marshal(redactString(string(body)))
+	} else if len(body) > 0 {
+		recorded.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+	return recorded
+}
+
+// httpResponse builds the HTTP response to replay for request.
+func (r Response) httpResponse(request *http.Request) (*http.Response, error) {
+	if r.BodyOmitted {
+		return nil, fmt.Errorf("%w: %d response of %s", ErrBodyNotRecorded, r.StatusCode, request.URL.Query().Get("function"))
+	}
+
+	body := []byte(r.Body)
+	if len(r.BodyBase64) != 0 {
+		decoded, err := base64.StdEncoding.DecodeString(r.BodyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot decode recorded response body: %w", err)
+		}
+		body = decoded
+	}
+
+	header := r.Headers.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       request,
+	}, nil
+}
+
+// RedactJSON replaces the values of password and token fields in a JSON document, and any
+// authentication token found in its strings, with Redacted. Bodies that are not valid JSON
+// are returned as a JSON string with tokens redacted.
+func RedactJSON(data []byte) json.RawMessage {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		quoted, _ := json.Marshal(redactString(string(data)))
+		return quoted
+	}
+
+	redacted, err := json.Marshal(redactValue(value))
+	if err != nil {
+		quoted, _ := json.Marshal(Redacted)
+		return quoted
+	}
+	return redacted
+}
+
+// redactValue redacts a decoded JSON value in place.
+func redactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if redactedKeys[strings.ToLower(key)] {
+				v[key] = Redacted
+				continue
+			}
+			v[key] = redactValue(field)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item)
+		}
+		return v
+	case string:
+		return redactString(v)
+	default:
+		return v
+	}
+}
+
+// redactString replaces every authentication token in s with Redacted.
+func redactString(s string) string {
+	return tokenPattern.ReplaceAllString(s, Redacted)
+}
+
+// compactJSON returns data without insignificant whitespace, for comparing bodies.
+func compactJSON(data []byte) []byte {
+	var buffer bytes.Buffer
+	if err := json.Compact(&buffer, data); err != nil {
+		return data
+	}
+	return buffer.Bytes()
+}
+
+// isJSON reports whether a Content-Type header is JSON.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
@@ -0,0 +1,301 @@
+package cassette_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+	"github.com/alchemicalkube/gofactory/api/cassette"
+)
+
+// token has the shape of a dedicated server authentication token.
+const token = "eyJwbCI6IkFkbWluaXN0cmF0b3IifQ==.de69df57bd3c9ce298365598f1f59a99267c8162db28b1235985f3b293744d3c"
+
+func TestRedactJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "password fields",
+			data: `{"function":"ClaimServer","data":{"ServerName":"Test","AdminPassword":"secret","clientPassword":"other"}}`,
+			want: `{"data":{"AdminPassword":"REDACTED","ServerName":"Test","clientPassword":"REDACTED"},"function":"ClaimServer"}`,
+		},
+		{
+			name: "token fields",
+			data: `{"data":{"authenticationToken":"abc","Token":"def"}}`,
+			want: `{"data":{"Token":"REDACTED","authenticationToken":"REDACTED"}}`,
+		},
+		{
+			name: "token in free text",
+			data: `{"data":{"commandResult":"New API Token: ` + token + `","returnValue":true}}`,
+			want: `{"data":{"commandResult":"New API Token: REDACTED","returnValue":true}}`,
+		},
+		{
+			name: "tokens in arrays",
+			data: `{"data":["` + token + `",{"password":"secret"}]}`,
+			want: `{"data":["REDACTED",{"password":"REDACTED"}]}`,
+		},
+		{
+			name: "numbers are kept",
+			data: `{"data":{"averageTickRate":29.999999999999996,"techTier":9007199254740993}}`,
+			want: `{"data":{"averageTickRate":29.999999999999996,"techTier":9007199254740993}}`,
+		},
+		{
+			name: "plain text body",
+			data: `token ` + token + ` expired`,
+			want: `"token REDACTED expired"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(cassette.RedactJSON([]byte(tt.data))); got != tt.want {
+				t.Errorf("RedactJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordRedacts(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	admin := server.Claim("Test", "secret-password")
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := cassette.New(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := server.NewClient(admin, api.WithMiddleware(recorder.Middleware()))
+
+	if err := client.PasswordLogin(ctx, api.ADMINISTRATOR_PRIVILEGE, "secret-password"); err != nil {
+		t.Fatalf("PasswordLogin() error = %v", err)
+	}
+	generated, err := client.GenerateAPIToken(ctx)
+	if err != nil {
+		t.Fatalf("GenerateAPIToken() error = %v", err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"secret-password", admin, client.Token(), generated.Token} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("cassette contains the secret %q", secret)
+		}
+	}
+
+	recorded, err := cassette.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	interactions := recorded.Interactions
+	if len(interactions) != 2 {
+		t.Fatalf("cassette holds %d interactions, want 2", len(interactions))
+	}
+
+	tests := []struct {
+		name string
+		got  func() string
+		want string
+	}{
+		{
+			name: "Authorization header",
+			got:  func() string { return interactions[1].Request.Headers.Get("Authorization") },
+			want: "Bearer " + cassette.Redacted,
+		},
+		{
+			name: "password in the request",
+			got:  func() string { return field(t, interactions[0].Request.Body, "data", "password") },
+			want: cassette.Redacted,
+		},
+		{
+			name: "token in the response",
+			got:  func() string { return field(t, interactions[0].Response.Body, "data", "authenticationToken") },
+			want: cassette.Redacted,
+		},
+		{
+			name: "token in the command output",
+			got:  func() string { return field(t, interactions[1].Response.Body, "data", "commandResult") },
+			want: "New API Token: " + cassette.Redacted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got(); got != tt.want {
+				t.Errorf("recorded %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// field returns the string at the given keys of a recorded JSON body.
+func field(t *testing.T, body json.RawMessage, keys ...string) string {
+	t.Helper()
+
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("cannot decode recorded body %s: %v", body, err)
+	}
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok {
+			t.Fatalf("recorded body %s has no %s", body, strings.Join(keys, "."))
+		}
+		value = object[key]
+	}
+	s, _ := value.(string)
+	return s
+}
+
+func TestReplay(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := cassette.New(path, cassette.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := server.NewClient(server.Claim("Test", "admin"), api.WithMiddleware(recorder.Middleware()))
+	if _, err := client.QueryServerState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.CreateNewGame(ctx, api.CreateNewGameRequestData{SessionName: "Fresh"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.QueryServerState(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayer, err := cassette.New(path, cassette.ModeReplay)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	replay := api.NewClient("https://recorded", api.WithMiddleware(replayer.Middleware()))
+
+	queryState := func() (string, error) {
+		state, err := replay.QueryServerState(ctx)
+		if err != nil {
+			return "", err
+		}
+		return state.ActiveSessionName, nil
+	}
+	createNewGame := func(sessionName string) func() (string, error) {
+		return func() (string, error) {
+			return "", replay.CreateNewGame(ctx, api.CreateNewGameRequestData{SessionName: sessionName})
+		}
+	}
+
+	// The steps run in order against the same replaying client.
+	steps := []struct {
+		name        string
+		call        func() (string, error)
+		wantSession string
+		wantErr     error
+	}{
+		{name: "first response", call: queryState},
+		{name: "responses in order", call: queryState, wantSession: "Fresh"},
+		{name: "last response repeats", call: queryState, wantSession: "Fresh"},
+		{name: "matching body", call: createNewGame("Fresh")},
+		{name: "different body", call: createNewGame("Other"), wantErr: cassette.ErrInteractionNotFound},
+		{
+			name:    "unrecorded function",
+			call:    func() (string, error) { return "", replay.RenameServer(ctx, "Renamed") },
+			wantErr: cassette.ErrInteractionNotFound,
+		},
+	}
+
+	for _, step := range steps {
+		session, err := step.call()
+		if !errors.Is(err, step.wantErr) {
+			t.Fatalf("%s: error = %v, want %v", step.name, err, step.wantErr)
+		}
+		if session != step.wantSession {
+			t.Errorf("%s: active session = %q, want %q", step.name, session, step.wantSession)
+		}
+	}
+}
+
+func TestRecordSaveDownload(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		wantOmitted bool
+		wantErr     error
+	}{
+		{
+			name: "small save is recorded",
+			size: 4096,
+		},
+		{
+			name:        "large save is not recorded",
+			size:        cassette.MaxBinaryBodySize + 1,
+			wantOmitted: true,
+			wantErr:     cassette.ErrBodyNotRecorded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			save := bytes.Repeat([]byte{0xc1}, tt.size)
+			server.AddSave("Session", "Save", save)
+			ctx := context.Background()
+
+			path := filepath.Join(t.TempDir(), "cassette.json")
+			recorder, err := cassette.New(path, cassette.ModeRecord)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := server.NewClient(server.Claim("Test", "admin"), api.WithMiddleware(recorder.Middleware()))
+
+			downloaded, err := client.DownloadSaveGame(ctx, "Save")
+			if err != nil || !bytes.Equal(downloaded, save) {
+				t.Fatalf("recording DownloadSaveGame() = %d bytes, %v, want %d bytes", len(downloaded), err, len(save))
+			}
+			if err := recorder.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			response := recorder.Cassette().Interactions[0].Response
+			if response.BodyOmitted != tt.wantOmitted || (len(response.BodyBase64) == 0) != tt.wantOmitted {
+				t.Errorf("recorded omitted %v with %d base64 bytes, want omitted %v",
+					response.BodyOmitted, len(response.BodyBase64), tt.wantOmitted)
+			}
+
+			replayer, err := cassette.New(path, cassette.ModeReplay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replay := api.NewClient("https://recorded", api.WithMiddleware(replayer.Middleware()))
+			downloaded, err = replay.DownloadSaveGame(ctx, "Save")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("replayed DownloadSaveGame() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && !bytes.Equal(downloaded, save) {
+				t.Errorf("replayed %d bytes, want the %d recorded bytes", len(downloaded), len(save))
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 )
 
@@ -40,27 +39,19 @@ type ClaimResponseData struct {
 // It updates the client's authentication token and privilege level upon success,
 // and verifies the server state after the claim is completed.
 func (c *GoFactoryClient) ClaimServer(ctx context.Context, claimData ClaimRequestData) error {
-	if c.currentPrivilege != INITIAL_ADMIN_PRIVILEGE && len(c.Token) != 0 {
+	if token, privilege := c.session(); privilege != INITIAL_ADMIN_PRIVILEGE && len(token) != 0 {
 		return fmt.Errorf("privilege must be set to %s and token must be empty", INITIAL_ADMIN_PRIVILEGE)
 	}
 
-	functionBody, err := json.Marshal(ClaimRequest{
-		Function: ClaimServerFunction,
-		Data:     claimData,
-	})
+	response, err := CallFunction[ClaimRequestData, ClaimResponseData](ctx, c, ClaimServerFunction, claimData)
 	if err != nil {
 		return err
 	}
-	newToken, err := CreateAndSendPostRequestWithHeaders[ClaimResponse](ctx, c, make(map[string]string), ClaimServerFunction, functionBody) // Send empty headers as we don't need authorization headers to claim
-	if err != nil {
-		return err
-	}
-	if newToken == nil {
+	if len(response.AuthenticationToken) == 0 {
 		return fmt.Errorf("new authentication Token returned is empty")
 	}
 
-	c.Token = newToken.Data.AuthenticationToken
-	c.currentPrivilege = API_TOKEN_PRIVILEGE
+	c.setSession(response.AuthenticationToken, tokenPrivilege(response.AuthenticationToken, ADMINISTRATOR_PRIVILEGE))
 
 	_, err = c.QueryServerState(ctx)
 	if err != nil {
@@ -3,28 +3,62 @@ package api
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 )
 
 // Version is the current version of the GoFactory API client.
 const Version = "1.0.0"
 
 // GoFactoryClient is a client for interacting with the Satisfactory dedicated server API.
+// It is safe for concurrent use by multiple goroutines, as long as its exported fields
+// are not changed after it is shared. Use Clone or WithToken to derive independent clients.
 type GoFactoryClient struct {
 	// URL is the base URL (& port, if necessary) of the Satisfactory dedicated server API.
 	URL string
 
-	// Token is the authentication token used for API requests.
-	Token string
+	// Client is the underlying HTTP client used for API requests.
+	Client *http.Client
+
+	// RetryPolicy controls how idempotent API calls are retried after a transient failure.
+	// Calls are never retried when it is nil.
+	RetryPolicy *RetryPolicy
+
+	// userAgent is the User-Agent header sent with every request.
+	userAgent string
+
+	// headers are the default headers sent with every request.
+	headers http.Header
+
+	// mu guards the session state and interceptors below, which change as the client logs in.
+	mu sync.RWMutex
+
+	// token is the authentication token used for API requests.
+	token string
 
 	// currentPrivilege represents the current privilege level of the client.
 	currentPrivilege string
 
-	// Client is the underlying HTTP client used for API requests.
-	Client *http.Client
+	// interceptors wrap every API call, the first one being the outermost.
+	interceptors []Interceptor
+
+	// credentials supplies credentials to authenticate again when the token is rejected.
+	credentials CredentialProvider
+
+	// commandPolicy restricts the console commands the client may run.
+	commandPolicy *CommandPolicy
+
+	// preflight enables checking the client's privilege before sending a call.
+	preflight bool
+
+	// authMu serialises re-authentication, so a rejected token is only replaced once.
+	authMu sync.Mutex
 }
 
 // ApiResponse is an empty interface used as a placeholder
@@ -33,32 +67,94 @@ type ApiResponse any
 
 // NewGoFactoryClient creates a new GoFactoryClient with the specified URL,
 // authentication token, and an option to skip TLS verification.
+// Use NewClient for more control over how the client is configured.
 func NewGoFactoryClient(url string, token string, skipVerify bool) *GoFactoryClient {
-	client := &GoFactoryClient{
-		URL:   url,
-		Token: token,
-		Client: &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
-			},
-		},
-		currentPrivilege: API_TOKEN_PRIVILEGE,
-	}
-	if len(token) == 0 {
-		client.currentPrivilege = INITIAL_ADMIN_PRIVILEGE
+	return NewClient(url, WithAuthToken(token), WithInsecureSkipVerify(skipVerify))
+}
+
+// Token returns the authentication token used for API requests. It replaces the former
+// exported Token field; use SetToken to assign a token.
+func (c *GoFactoryClient) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// Privilege returns the privilege level the client currently holds.
+func (c *GoFactoryClient) Privilege() Privilege {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Privilege(c.currentPrivilege)
+}
+
+// SetToken replaces the authentication token used for API requests, and takes the client's
+// privilege from the token. An empty token makes the client unauthenticated until it logs in.
+func (c *GoFactoryClient) SetToken(token string) {
+	c.setSession(token, tokenPrivilege(token, API_TOKEN_PRIVILEGE))
+}
+
+// setSession replaces the token and privilege together, so concurrent calls never see one without the other.
+func (c *GoFactoryClient) setSession(token string, privilege string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.currentPrivilege = privilege
+}
+
+// session returns the token and privilege together.
+func (c *GoFactoryClient) session() (token string, privilege string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token, c.currentPrivilege
+}
+
+// Clone returns a new client with the same configuration and session as c, which can then log in
+// or change its token without affecting c. The clients share the underlying *http.Client and RetryPolicy.
+func (c *GoFactoryClient) Clone() *GoFactoryClient {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &GoFactoryClient{
+		URL:              c.URL,
+		Client:           c.Client,
+		RetryPolicy:      c.RetryPolicy,
+		userAgent:        c.userAgent,
+		headers:          c.headers.Clone(),
+		token:            c.token,
+		currentPrivilege: c.currentPrivilege,
+		interceptors:     slices.Clone(c.interceptors),
+		credentials:      c.credentials,
+		preflight:        c.preflight,
+		commandPolicy:    c.commandPolicy,
 	}
-	return client
+}
+
+// WithToken returns a clone of c that uses the given authentication token.
+func (c *GoFactoryClient) WithToken(token string) *GoFactoryClient {
+	clone := c.Clone()
+	clone.SetToken(token)
+	return clone
 }
 
 // CreatePostRequest creates a HTTP POST request to call the specified API function.
 func (c *GoFactoryClient) CreatePostRequest(functionName string, apiFunction []byte) (*http.Request, error) {
-	request, err := http.NewRequest(http.MethodPost, c.URL+"/api/v1/?function="+functionName, bytes.NewBuffer(apiFunction))
+	return c.newPostRequest(functionName, bytes.NewBuffer(apiFunction), "application/json")
+}
+
+// newPostRequest creates an HTTP POST request to call the specified API function with a body
+// of the given content type. The client's token is sent, if it has one, unless the function
+// is used to authenticate, as the server rejects those calls when the token is no longer valid.
+func (c *GoFactoryClient) newPostRequest(functionName string, body io.Reader, contentType string) (*http.Request, error) {
+	request, err := http.NewRequest(http.MethodPost, c.URL+"/api/v1/?function="+functionName, body)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("cannot create %s request: %w", functionName, err)
 	}
+	c.setDefaultHeaders(request)
 
-	request.Header.Set("Authorization", "Bearer "+c.Token)
-	request.Header.Add("Content-Type", "application/json")
+	if token := c.Token(); len(token) != 0 && !unauthenticatedFunctions[functionName] {
+		request.Header.Set("Authorization", "Bearer "+token)
+	}
+	request.Header.Add("Content-Type", contentType)
 
 	return request, nil
 }
@@ -68,8 +164,9 @@ func (c *GoFactoryClient) CreatePostRequest(functionName string, apiFunction []b
 func (c *GoFactoryClient) CreatePostRequestWithHeaders(headers map[string]string, functionName string, apiFunction []byte) (*http.Request, error) {
 	request, err := http.NewRequest(http.MethodPost, c.URL+"/api/v1/?function="+functionName, bytes.NewBuffer(apiFunction))
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("cannot create %s request: %w", functionName, err)
 	}
+	c.setDefaultHeaders(request)
 
 	for header, headerValue := range headers {
 		request.Header.Add(header, headerValue)
@@ -78,33 +175,146 @@ func (c *GoFactoryClient) CreatePostRequestWithHeaders(headers map[string]string
 	return request, nil
 }
 
+// setDefaultHeaders adds the client's User-Agent and default headers to the request.
+func (c *GoFactoryClient) setDefaultHeaders(request *http.Request) {
+	if len(c.userAgent) != 0 {
+		request.Header.Set("User-Agent", c.userAgent)
+	}
+	for header, values := range c.headers {
+		for _, value := range values {
+			request.Header.Add(header, value)
+		}
+	}
+}
+
+// errNilResponse is reported when the HTTP client returns neither a response nor an error.
+var errNilResponse = errors.New("http client returned a nil response")
+
 // SendPostRequest sends the provided HTTP request to the server and decodes the response
-// into the given ApiResponse. It also handles API errors returned by the server.
+// into the given ApiResponse. Error statuses are returned as an *APIError, and failures to
+// reach the server as a *TransportError. Idempotent functions are retried according to the
+// client's RetryPolicy. The call passes through the client's interceptors. If the server rejects
+// the token and the client has a CredentialProvider, the client authenticates again and sends the call once more.
 func (c *GoFactoryClient) SendPostRequest(ctx context.Context, request *http.Request, response ApiResponse) error {
-	resp, err := c.Client.Do(request.WithContext(ctx))
+	if request == nil {
+		return errors.New("cannot send a nil request")
+	}
+	return c.send(ctx, request, decodeResponse(response))
+}
+
+// responseHandler reads a successful response of an attempt, recording the bytes it reads on the call.
+type responseHandler func(resp *http.Response, call *Call) error
+
+// send checks the client's privilege, then sends the request and passes the successful response to handle,
+// authenticating again and sending the request once more if the server rejects the token.
+func (c *GoFactoryClient) send(ctx context.Context, request *http.Request, handle responseHandler) error {
+	if err := c.preflightPrivilege(request.URL.Query().Get("function")); err != nil {
+		return err
+	}
+
+	err := c.sendPostRequest(ctx, request, handle)
+	if !c.shouldReauthenticate(request, err) {
+		return err
+	}
+
+	rejectedToken := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+	if authErr := c.reauthenticate(ctx, rejectedToken); authErr != nil {
+		return fmt.Errorf("%w (cannot authenticate again: %w)", err, authErr)
+	}
+
+	retry, retryErr := withToken(ctx, request, c.Token())
+	if retryErr != nil {
+		return err
+	}
+	return c.sendPostRequest(ctx, retry, handle)
+}
+
+// sendPostRequest sends the request through the client's interceptors, retrying it according to the RetryPolicy.
+// The request sent is the call's, which interceptors may have replaced.
+func (c *GoFactoryClient) sendPostRequest(ctx context.Context, request *http.Request, handle responseHandler) error {
+	call := c.newCall(request)
+
+	return c.invoke(ctx, call, func(ctx context.Context, call *Call) error {
+		request := call.Request
+		if request == nil {
+			return errors.New("cannot send a nil request")
+		}
+		if !c.RetryPolicy.allows(call.Function) || request.GetBody == nil {
+			return c.sendPostRequestOnce(ctx, request, call, handle)
+		}
+
+		return c.RetryPolicy.do(ctx, func(attempt int) error {
+			if attempt == 0 {
+				return c.sendPostRequestOnce(ctx, request, call, handle)
+			}
+
+			body, err := request.GetBody()
+			if err != nil {
+				return err
+			}
+			retry := request.Clone(ctx)
+			retry.Body = body
+
+			return c.sendPostRequestOnce(ctx, retry, call, handle)
+		})
+	})
+}
+
+// sendPostRequestOnce performs a single attempt of SendPostRequest, passing a successful response to handle.
+// It records the attempt, the bytes sent and received and the response status on the call.
+func (c *GoFactoryClient) sendPostRequestOnce(ctx context.Context, request *http.Request, call *Call, handle responseHandler) (err error) {
+	functionName := call.Function
+	call.Attempts++
+	call.StatusCode = 0
+	call.RequestBytes = 0
+	call.ResponseBytes = 0
+
+	sent := request.WithContext(ctx)
+	if request.Body != nil && request.Body != http.NoBody {
+		body := &countingBody{body: request.Body}
+		sent.Body = body
+		defer func() {
+			call.RequestBytes = body.count.Load()
+		}()
+	}
+
+	resp, err := c.Client.Do(sent)
 	if err != nil {
-		log.Fatal(err)
+		return &TransportError{Function: functionName, Err: err}
+	}
+	if resp == nil {
+		return &TransportError{Function: functionName, Err: errNilResponse}
 	}
+	call.StatusCode = resp.StatusCode
 	defer func() {
-		if berr := resp.Body.Close(); err != nil {
-			err = berr
+		if berr := resp.Body.Close(); berr != nil && err == nil {
+			err = &TransportError{Function: functionName, Err: fmt.Errorf("cannot close response body: %w", berr)}
 		}
 	}()
 
-	if resp.StatusCode != 200 && resp.StatusCode != 204 {
-		var apiError APIError
-		err := json.NewDecoder(resp.Body).Decode(&apiError)
-		if err != nil {
-			return err
-		}
-		return &apiError
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return decodeAPIError(functionName, resp)
 	}
+	return handle(resp, call)
+}
+
+// decodeResponse returns a responseHandler decoding the JSON body of a response into response,
+// which may be nil to discard it.
+func decodeResponse(response ApiResponse) responseHandler {
+	return func(resp *http.Response, call *Call) error {
+		if resp.StatusCode == http.StatusNoContent || response == nil {
+			return nil
+		}
 
-	if resp.StatusCode == 204 {
+		err := json.NewDecoder(&countingReader{reader: resp.Body, count: &call.ResponseBytes}).Decode(response)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("cannot decode %s response: %w", call.Function, err)
+		}
 		return nil
 	}
-
-	return json.NewDecoder(resp.Body).Decode(response)
 }
 
 // CreateAndSendPostRequest creates a HTTP POST request for the given API function,
@@ -0,0 +1,141 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+// countingCredentials returns a CredentialProvider logging in with the admin password, and counts its logins.
+func countingCredentials(logins *atomic.Int32, password string) api.CredentialProvider {
+	return api.CredentialProviderFunc(func(context.Context) (api.Credentials, error) {
+		logins.Add(1)
+		return api.Credentials{Privilege: api.ADMINISTRATOR_PRIVILEGE, Password: password}, nil
+	})
+}
+
+func TestClientReauthenticatesOnce(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	token := server.Claim("Test", "admin")
+
+	var logins atomic.Int32
+	client := server.NewClient(token, api.WithCredentialProvider(countingCredentials(&logins, "admin")))
+	server.RevokeToken(token)
+
+	const callers = 16
+	errs := make(chan error, callers)
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.QueryServerState(context.Background())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("QueryServerState() error = %v", err)
+		}
+	}
+	if got := logins.Load(); got != 1 {
+		t.Errorf("logins = %d, want 1", got)
+	}
+	if client.Token() == token || client.Privilege() != api.ADMINISTRATOR_PRIVILEGE {
+		t.Errorf("client holds token %q with privilege %s, want a new Administrator token", client.Token(), client.Privilege())
+	}
+}
+
+func TestClientConcurrentUse(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	token := server.Claim("Test", "admin")
+
+	var logins atomic.Int32
+	client := server.NewClient(token, api.WithCredentialProvider(countingCredentials(&logins, "admin")))
+	ctx := context.Background()
+
+	const iterations = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, 8*iterations)
+	run := func(task func(i int) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range iterations {
+				if err := task(i); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+
+	for range 4 {
+		run(func(int) error {
+			_, err := client.QueryServerState(ctx)
+			return err
+		})
+	}
+	run(func(int) error {
+		client.SetToken(server.IssueToken(api.ADMINISTRATOR_PRIVILEGE))
+		return nil
+	})
+	run(func(int) error {
+		clone := client.WithToken(server.IssueToken(api.CLIENT_PRIVILEGE))
+		if clone.Privilege() != api.CLIENT_PRIVILEGE {
+			t.Errorf("WithToken() clone privilege = %s, want %s", clone.Privilege(), api.CLIENT_PRIVILEGE)
+		}
+		_, err := clone.QueryServerState(ctx)
+		return err
+	})
+	// Only one token is revoked, so every rejected call is sent again with a valid token.
+	run(func(i int) error {
+		if i == iterations/2 {
+			server.RevokeToken(client.Token())
+		}
+		_, err := client.GetServerOptions(ctx)
+		return err
+	})
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent call error = %v", err)
+	}
+	if got := logins.Load(); got > 1 {
+		t.Errorf("logins = %d, want at most 1", got)
+	}
+	if client.Privilege() != api.ADMINISTRATOR_PRIVILEGE {
+		t.Errorf("client privilege = %s, want %s", client.Privilege(), api.ADMINISTRATOR_PRIVILEGE)
+	}
+}
+
+func TestVerifyTokenDoesNotReauthenticate(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	token := server.Claim("Test", "admin")
+
+	var logins atomic.Int32
+	client := server.NewClient(token, api.WithCredentialProvider(countingCredentials(&logins, "admin")))
+	server.RevokeToken(token)
+
+	if _, err := client.VerifyToken(context.Background()); !errors.Is(err, api.ErrInvalidToken) {
+		t.Errorf("VerifyToken() error = %v, want %v", err, api.ErrInvalidToken)
+	}
+	if got := logins.Load(); got != 0 {
+		t.Errorf("logins = %d, want 0", got)
+	}
+	if client.Token() != token {
+		t.Errorf("client token was replaced after VerifyToken")
+	}
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Console commands understood by the dedicated server, with a typed helper on GoFactoryClient each.
+const (
+	// GenerateAPITokenCommand generates a new API token. See GenerateAPIToken.
+	GenerateAPITokenCommand = "server.GenerateAPIToken"
+
+	// InvalidateAllAPITokensCommand revokes every API token. See InvalidateAllAPITokens.
+	InvalidateAllAPITokensCommand = "server.InvalidateAllAPITokens"
+
+	// SaveGameCommand saves the running session under a save name. See SaveGameCommandResult.
+	SaveGameCommand = "server.SaveGame"
+
+	// QueryStateCommand prints the state of the server. See QueryStateCommandResult.
+	QueryStateCommand = "server.QueryState"
+)
+
+// ErrCommandNotAllowed is returned by RunCommand when the client's CommandPolicy rejects a command.
+var ErrCommandNotAllowed = errors.New("gofactory api error: console command not allowed by policy")
+
+// ErrUnexpectedCommandOutput is returned by the typed console command helpers when the server's
+// output cannot be parsed, for example because a mod changed it.
+var ErrUnexpectedCommandOutput = errors.New("gofactory api error: unexpected console command output")
+
+// CommandPolicy decides which console commands a client may run. Commands are matched by their
+// name, the first word of the command, against path.Match patterns such as "server.*", ignoring case.
+// A command is rejected if it matches a Deny pattern, or if Allow is not empty and it matches none of its patterns.
+// The policy applies to every command, including those sent by the typed helpers.
+type CommandPolicy struct {
+	// Allow lists the commands that may be run. Every command is allowed when it is empty.
+	Allow []string
+
+	// Deny lists the commands that may never be run, even if allowed.
+	Deny []string
+}
+
+// Check returns an error matching ErrCommandNotAllowed if the policy rejects the command.
+func (p *CommandPolicy) Check(command string) error {
+	if p == nil {
+		return nil
+	}
+
+	name := commandName(command)
+	if matchCommand(p.Deny, name) {
+		return fmt.Errorf("%w: %s is denied", ErrCommandNotAllowed, name)
+	}
+	if len(p.Allow) != 0 && !matchCommand(p.Allow, name) {
+		return fmt.Errorf("%w: %s is not allowed", ErrCommandNotAllowed, name)
+	}
+	return nil
+}
+
+// WithCommandPolicy restricts the console commands the client may run with RunCommand.
+func WithCommandPolicy(policy *CommandPolicy) Option {
+	return func(o *clientOptions) {
+		o.commandPolicy = policy
+	}
+}
+
+// commandName returns the lowercase first word of a console command.
+func commandName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(fields[0])
+}
+
+// matchCommand reports whether name matches one of the patterns.
+func matchCommand(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(strings.ToLower(pattern), name); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// APITokenResult is the result of the server.GenerateAPIToken console command.
+type APITokenResult struct {
+	// Token is the new API token.
+	Token string
+
+	// Claims are the claims decoded from the token.
+	Claims *TokenClaims
+}
+
+// GenerateAPIToken runs the server.GenerateAPIToken console command and returns the new API token.
+func (c *GoFactoryClient) GenerateAPIToken(ctx context.Context) (*APITokenResult, error) {
+	result, err := c.RunCommand(ctx, GenerateAPITokenCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	_, token, found := strings.Cut(result.Output, "New API Token:")
+	token = strings.TrimSpace(token)
+	if !found || len(token) == 0 {
+		return nil, fmt.Errorf("%w: %s: %q", ErrUnexpectedCommandOutput, GenerateAPITokenCommand, result.Output)
+	}
+
+	claims, err := ParseToken(token)
+	if err != nil {
+		return nil, err
+	}
+	return &APITokenResult{Token: token, Claims: claims}, nil
+}
+
+// InvalidateAllAPITokens runs the server.InvalidateAllAPITokens console command, revoking every API token
+// issued by the server, including the client's own if it uses one.
+func (c *GoFactoryClient) InvalidateAllAPITokens(ctx context.Context) error {
+	result, err := c.RunCommand(ctx, InvalidateAllAPITokensCommand)
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(strings.ToLower(result.Output), "invalidated") {
+		return fmt.Errorf("%w: %s: %q", ErrUnexpectedCommandOutput, InvalidateAllAPITokensCommand, result.Output)
+	}
+	return nil
+}
+
+// SaveGameCommandResult is the result of the server.SaveGame console command.
+type SaveGameCommandResult struct {
+	// SaveName is the name the game was saved under.
+	SaveName string
+
+	// Output is the console output of the command.
+	Output string
+}
+
+// SaveGameWithCommand runs the server.SaveGame console command to save the running session under saveName.
+// Unlike SaveGame, the server reports failures in the command output, which are returned as errors.
+func (c *GoFactoryClient) SaveGameWithCommand(ctx context.Context, saveName string) (*SaveGameCommandResult, error) {
+	if len(strings.TrimSpace(saveName)) == 0 || strings.ContainsAny(saveName, " \t\n") {
+		return nil, fmt.Errorf("invalid save name %q: it must be a single word", saveName)
+	}
+
+	result, err := c.RunCommand(ctx, SaveGameCommand+" "+saveName)
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.ReturnValue || !strings.Contains(result.Output, saveName) {
+		return nil, fmt.Errorf("%w: %s: %q", ErrUnexpectedCommandOutput, SaveGameCommand, result.Output)
+	}
+	return &SaveGameCommandResult{SaveName: saveName, Output: result.Output}, nil
+}
+
+// QueryStateCommandResult is the result of the server.QueryState console command.
+type QueryStateCommandResult struct {
+	// Values are the "Key: Value" lines of the output, keyed by their key.
+	Values map[string]string
+
+	// Output is the console output of the command.
+	Output string
+}
+
+// QueryStateWithCommand runs the server.QueryState console command and parses its "Key: Value" lines.
+// QueryServerState returns the same information as typed fields through the HTTPS API.
+func (c *GoFactoryClient) QueryStateWithCommand(ctx context.Context) (*QueryStateCommandResult, error) {
+	result, err := c.RunCommand(ctx, QueryStateCommand)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for _, line := range strings.Split(result.Output, "\n") {
+		key, value, found := strings.Cut(line, ":")
+		if !found || len(strings.TrimSpace(key)) == 0 {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w: %s: %q", ErrUnexpectedCommandOutput, QueryStateCommand, result.Output)
+	}
+
+	return &QueryStateCommandResult{Values: values, Output: result.Output}, nil
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Credentials are used by the client to authenticate again after its token was rejected.
+// If Token is set it is used as is, otherwise the client logs in with Password, or
+// without a password when Password is empty.
+type Credentials struct {
+	// Token is an authentication token to use directly, such as an API token.
+	Token string
+
+	// Privilege is the privilege level to log in with. Defaults to ADMINISTRATOR_PRIVILEGE.
+	// It is ignored for tokens that encode their own privilege level.
+	Privilege string
+
+	// Password is the password to log in with.
+	Password string
+}
+
+// CredentialProvider supplies credentials to a client when the server rejects its token with
+// invalid_token, for example after SetAdminPassword rotated tokens or the server was reinstalled.
+type CredentialProvider interface {
+	// Credentials returns the credentials to authenticate with.
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc adapts a function to a CredentialProvider, for example
+// to fetch a password from a secret store or prompt for it.
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+// Credentials calls f.
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// PasswordCredentials returns a CredentialProvider that logs in with a password for the given privilege.
+func PasswordCredentials(privilege string, password string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{Privilege: privilege, Password: password}, nil
+	})
+}
+
+// TokenCredentials returns a CredentialProvider that switches to the given token, such as an API token
+// kept as a fallback for a session token.
+func TokenCredentials(token string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{Token: token}, nil
+	})
+}
+
+// WithCredentialProvider makes the client authenticate again with the provider's credentials when
+// a call fails with invalid_token, and then send the call once more.
+func WithCredentialProvider(provider CredentialProvider) Option {
+	return func(o *clientOptions) {
+		o.credentials = provider
+	}
+}
+
+// unauthenticatedFunctions are the API functions that do not send the client's token,
+// so they are never retried after re-authenticating.
+var unauthenticatedFunctions = map[string]bool{
+	HealthCheckFunction:       true,
+	PasswordlessLoginFunction: true,
+	PasswordLoginFunction:     true,
+}
+
+// shouldReauthenticate reports whether the call that sent request may be sent again after
+// authenticating with the client's credential provider. VerifyAuthenticationToken is never
+// sent again, so that VerifyToken reports whether the token the client holds is valid.
+func (c *GoFactoryClient) shouldReauthenticate(request *http.Request, err error) bool {
+	function := request.URL.Query().Get("function")
+	return c.credentials != nil &&
+		errors.Is(err, ErrInvalidToken) &&
+		request.GetBody != nil &&
+		len(request.Header.Get("Authorization")) != 0 &&
+		!unauthenticatedFunctions[function] &&
+		function != VerifyAuthTokenFunction
+}
+
+// reauthenticate replaces the rejected token with one obtained from the credential provider.
+// When several calls are rejected at once, only the first one authenticates, and the others
+// use its new token.
+func (c *GoFactoryClient) reauthenticate(ctx context.Context, rejectedToken string) error {
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+
+	if c.Token() != rejectedToken {
+		return nil
+	}
+
+	credentials, err := c.credentials.Credentials(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot get credentials: %w", err)
+	}
+
+	if len(credentials.Token) != 0 {
+		if credentials.Token == rejectedToken {
+			return errors.New("credential provider returned the rejected token")
+		}
+		privilege := credentials.Privilege
+		if len(privilege) == 0 {
+			privilege = API_TOKEN_PRIVILEGE
+		}
+		c.setSession(credentials.Token, tokenPrivilege(credentials.Token, privilege))
+		return nil
+	}
+
+	privilege := credentials.Privilege
+	if len(privilege) == 0 {
+		privilege = ADMINISTRATOR_PRIVILEGE
+	}
+	if len(credentials.Password) == 0 {
+		return c.PasswordlessLogin(ctx, privilege)
+	}
+	return c.PasswordLogin(ctx, privilege, credentials.Password)
+}
+
+// withToken returns a copy of request that sends the given token.
+func withToken(ctx context.Context, request *http.Request, token string) (*http.Request, error) {
+	body, err := request.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	retry := request.Clone(ctx)
+	retry.Body = body
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return retry, nil
+}
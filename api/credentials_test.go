@@ -0,0 +1,113 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+func TestReauthentication(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// provider returns the client's credential provider, given a valid fallback token.
+		provider      func(fallback string) api.CredentialProvider
+		wantErr       error
+		wantErrText   string
+		wantPrivilege api.Privilege
+	}{
+		{
+			name: "password credentials",
+			provider: func(string) api.CredentialProvider {
+				return api.PasswordCredentials(api.ADMINISTRATOR_PRIVILEGE, "admin")
+			},
+			wantPrivilege: api.ADMINISTRATOR_PRIVILEGE,
+		},
+		{
+			name:          "token credentials",
+			provider:      api.TokenCredentials,
+			wantPrivilege: api.API_TOKEN_PRIVILEGE,
+		},
+		{
+			name: "passwordless client credentials",
+			provider: func(string) api.CredentialProvider {
+				return api.PasswordCredentials(api.CLIENT_PRIVILEGE, "")
+			},
+			wantPrivilege: api.CLIENT_PRIVILEGE,
+		},
+		{
+			name: "wrong password",
+			provider: func(string) api.CredentialProvider {
+				return api.PasswordCredentials(api.ADMINISTRATOR_PRIVILEGE, "wrong")
+			},
+			wantErr:     api.ErrWrongPassword,
+			wantErrText: "cannot authenticate again",
+		},
+		{
+			name: "provider error",
+			provider: func(string) api.CredentialProvider {
+				return api.CredentialProviderFunc(func(context.Context) (api.Credentials, error) {
+					return api.Credentials{}, errors.New("secret store unavailable")
+				})
+			},
+			wantErr:     api.ErrInvalidToken,
+			wantErrText: "secret store unavailable",
+		},
+		{
+			name:        "no provider",
+			provider:    func(string) api.CredentialProvider { return nil },
+			wantErr:     api.ErrInvalidToken,
+			wantErrText: "not valid",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			token := server.Claim("Test", "admin")
+			fallback := server.IssueToken(api.API_TOKEN_PRIVILEGE)
+
+			var opts []api.Option
+			if provider := tt.provider(fallback); provider != nil {
+				opts = append(opts, api.WithCredentialProvider(provider))
+			}
+			client := server.NewClient(token, opts...)
+			server.RevokeToken(token)
+
+			_, err := client.QueryServerState(context.Background())
+			if !errors.Is(err, tt.wantErr) || (err != nil && !strings.Contains(err.Error(), tt.wantErrText)) {
+				t.Fatalf("QueryServerState() error = %v, want %v containing %q", err, tt.wantErr, tt.wantErrText)
+			}
+			if tt.wantErr != nil {
+				if client.Token() != token {
+					t.Error("client token was replaced after a failed re-authentication")
+				}
+				return
+			}
+
+			if client.Token() == token || client.Privilege() != tt.wantPrivilege {
+				t.Errorf("client holds privilege %s after re-authenticating, want a new %s token",
+					client.Privilege(), tt.wantPrivilege)
+			}
+		})
+	}
+}
+
+func TestReauthenticationRejectsSameToken(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	token := server.Claim("Test", "admin")
+
+	client := server.NewClient(token, api.WithCredentialProvider(api.TokenCredentials(token)))
+	server.RevokeToken(token)
+
+	_, err := client.QueryServerState(context.Background())
+	if !errors.Is(err, api.ErrInvalidToken) || !strings.Contains(err.Error(), "rejected token") {
+		t.Errorf("QueryServerState() error = %v, want %v naming the rejected token", err, api.ErrInvalidToken)
+	}
+}
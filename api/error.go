@@ -1,25 +1,203 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
 )
 
+// Error codes returned by the Satisfactory dedicated server in the errorCode field of an error response.
+const (
+	// ErrorCodeInsufficientScope is returned when the token's privilege is too low for the called function.
+	ErrorCodeInsufficientScope = "insufficient_scope"
+
+	// ErrorCodeInvalidToken is returned when the authentication token is malformed, expired or revoked.
+	ErrorCodeInvalidToken = "invalid_token"
+
+	// ErrorCodeMissingParams is returned when a required parameter is missing from the request data.
+	ErrorCodeMissingParams = "missing_params"
+
+	// ErrorCodeInvalidParams is returned when a parameter in the request data has an invalid value.
+	ErrorCodeInvalidParams = "invalid_params"
+
+	// ErrorCodeUnknownFunction is returned when the called API function does not exist.
+	ErrorCodeUnknownFunction = "unknown_function"
+
+	// ErrorCodeWrongPassword is returned when a password login is attempted with the wrong password.
+	ErrorCodeWrongPassword = "wrong_password"
+
+	// ErrorCodePasswordlessLoginNotPossible is returned when passwordless login is not allowed for the
+	// requested privilege, because the server is claimed or a client password is set.
+	ErrorCodePasswordlessLoginNotPossible = "passwordless_login_not_possible"
+
+	// ErrorCodeServerClaimed is returned when claiming a server that has already been claimed.
+	ErrorCodeServerClaimed = "server_claimed"
+
+	// ErrorCodeServerNotClaimed is returned when calling a function that requires a claimed server.
+	ErrorCodeServerNotClaimed = "server_not_claimed"
+
+	// ErrorCodePasswordInUse is returned when the client and admin password would be the same.
+	ErrorCodePasswordInUse = "password_in_use"
+
+	// ErrorCodeCannotResetAdminPassword is returned when trying to remove the admin password.
+	ErrorCodeCannotResetAdminPassword = "cannot_reset_admin_password"
+
+	// ErrorCodeFileNotFound is returned when the requested save file or session does not exist.
+	ErrorCodeFileNotFound = "file_not_found"
+
+	// ErrorCodeInvalidSaveGame is returned when an uploaded save game cannot be read.
+	ErrorCodeInvalidSaveGame = "invalid_save_game"
+
+	// ErrorCodeSaveGameLoadFailed is returned when the server fails to load a save game.
+	ErrorCodeSaveGameLoadFailed = "save_game_load_failed"
+
+	// ErrorCodeNoActiveSession is returned when a function needs a running game but none is loaded.
+	ErrorCodeNoActiveSession = "no_active_session"
+)
+
+// Error codes reported by ErrorCodeOf for errors that were not returned by the server.
+const (
+	// ErrorCodeTransport is reported when the server could not be reached.
+	ErrorCodeTransport = "transport"
+
+	// ErrorCodeCanceled is reported when the call's context was cancelled or timed out.
+	ErrorCodeCanceled = "canceled"
+
+	// ErrorCodeOther is reported for every other error, such as a response that could not be decoded.
+	ErrorCodeOther = "other"
+)
+
+// ErrorCodeOf returns a short code describing err, for use in logs, metrics and traces: the error code
+// returned by the server, "http_" followed by the status if the server returned no code, or one of
+// ErrorCodeTransport, ErrorCodeCanceled and ErrorCodeOther. It returns "" for a nil error.
+func ErrorCodeOf(err error) string {
+	var apiError *APIError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &apiError) && len(apiError.StatusCode) != 0:
+		return apiError.StatusCode
+	case apiError != nil:
+		return "http_" + strconv.Itoa(apiError.HTTPStatusCode)
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return ErrorCodeCanceled
+	case errors.Is(err, ErrTransport):
+		return ErrorCodeTransport
+	default:
+		return ErrorCodeOther
+	}
+}
+
+// Sentinel errors matching the API error codes. An *APIError returned by the client
+// matches the sentinel for its error code with errors.Is.
+var (
+	ErrInsufficientScope            = errors.New("gofactory api error: " + ErrorCodeInsufficientScope)
+	ErrInvalidToken                 = errors.New("gofactory api error: " + ErrorCodeInvalidToken)
+	ErrMissingParams                = errors.New("gofactory api error: " + ErrorCodeMissingParams)
+	ErrInvalidParams                = errors.New("gofactory api error: " + ErrorCodeInvalidParams)
+	ErrUnknownFunction              = errors.New("gofactory api error: " + ErrorCodeUnknownFunction)
+	ErrWrongPassword                = errors.New("gofactory api error: " + ErrorCodeWrongPassword)
+	ErrPasswordlessLoginNotPossible = errors.New("gofactory api error: " + ErrorCodePasswordlessLoginNotPossible)
+	ErrServerClaimed                = errors.New("gofactory api error: " + ErrorCodeServerClaimed)
+	ErrServerNotClaimed             = errors.New("gofactory api error: " + ErrorCodeServerNotClaimed)
+	ErrPasswordInUse                = errors.New("gofactory api error: " + ErrorCodePasswordInUse)
+	ErrCannotResetAdminPassword     = errors.New("gofactory api error: " + ErrorCodeCannotResetAdminPassword)
+	ErrFileNotFound                 = errors.New("gofactory api error: " + ErrorCodeFileNotFound)
+	ErrInvalidSaveGame              = errors.New("gofactory api error: " + ErrorCodeInvalidSaveGame)
+	ErrSaveGameLoadFailed           = errors.New("gofactory api error: " + ErrorCodeSaveGameLoadFailed)
+	ErrNoActiveSession              = errors.New("gofactory api error: " + ErrorCodeNoActiveSession)
+)
+
+// errorCodeSentinels maps every known error code to its sentinel error.
+var errorCodeSentinels = map[string]error{
+	ErrorCodeInsufficientScope:            ErrInsufficientScope,
+	ErrorCodeInvalidToken:                 ErrInvalidToken,
+	ErrorCodeMissingParams:                ErrMissingParams,
+	ErrorCodeInvalidParams:                ErrInvalidParams,
+	ErrorCodeUnknownFunction:              ErrUnknownFunction,
+	ErrorCodeWrongPassword:                ErrWrongPassword,
+	ErrorCodePasswordlessLoginNotPossible: ErrPasswordlessLoginNotPossible,
+	ErrorCodeServerClaimed:                ErrServerClaimed,
+	ErrorCodeServerNotClaimed:             ErrServerNotClaimed,
+	ErrorCodePasswordInUse:                ErrPasswordInUse,
+	ErrorCodeCannotResetAdminPassword:     ErrCannotResetAdminPassword,
+	ErrorCodeFileNotFound:                 ErrFileNotFound,
+	ErrorCodeInvalidSaveGame:              ErrInvalidSaveGame,
+	ErrorCodeSaveGameLoadFailed:           ErrSaveGameLoadFailed,
+	ErrorCodeNoActiveSession:              ErrNoActiveSession,
+}
+
+// Sentinel errors matching the HTTP status of a failed API call. An *APIError matches
+// the sentinel for its HTTP status with errors.Is, whether or not the server sent an error code.
+var (
+	ErrBadRequest   = errors.New("gofactory api error: bad request")
+	ErrUnauthorized = errors.New("gofactory api error: unauthorized")
+	ErrForbidden    = errors.New("gofactory api error: forbidden")
+	ErrNotFound     = errors.New("gofactory api error: not found")
+
+	// ErrServerError matches any 5xx HTTP status.
+	ErrServerError = errors.New("gofactory api error: server error")
+)
+
+// ErrTransport matches any *TransportError with errors.Is.
+var ErrTransport = errors.New("gofactory transport error")
+
+// APIError is the error returned when the Satisfactory dedicated server responds with
+// an error status. It matches the ErrorCode and HTTP status sentinels with errors.Is.
 type APIError struct {
-	StatusCode string      `json:"errorCode"`
-	Message    string      `json:"errorMessage"`
-	Data       interface{} `json:"errorData,omitempty"`
+	// StatusCode is the errorCode returned by the server, such as "invalid_token".
+	// It is empty when the server did not return a JSON error body.
+	StatusCode string `json:"errorCode"`
+
+	// Message is the errorMessage returned by the server, or the raw response body
+	// when it was not a JSON error.
+	Message string `json:"errorMessage"`
+
+	// Data is any additional errorData returned by the server.
+	Data interface{} `json:"errorData,omitempty"`
+
+	// HTTPStatusCode is the HTTP status code of the response.
+	HTTPStatusCode int `json:"-"`
 }
 
 func (e *APIError) Error() string {
 	switch e.StatusCode {
-	case "invalid_token":
+	case ErrorCodeInvalidToken:
 		return e.invalidToken()
+	case "":
+		return e.httpStatusMessage()
 	default:
 		return e.defaultMessage()
 	}
 }
 
+// Is reports whether the error matches target, which is either the sentinel
+// for the API error code or the sentinel for the HTTP status.
+func (e *APIError) Is(target error) bool {
+	if sentinel, ok := errorCodeSentinels[e.StatusCode]; ok && target == sentinel {
+		return true
+	}
+
+	switch {
+	case e.HTTPStatusCode == http.StatusBadRequest:
+		return target == ErrBadRequest
+	case e.HTTPStatusCode == http.StatusUnauthorized:
+		return target == ErrUnauthorized
+	case e.HTTPStatusCode == http.StatusForbidden:
+		return target == ErrForbidden
+	case e.HTTPStatusCode == http.StatusNotFound:
+		return target == ErrNotFound
+	case e.HTTPStatusCode >= 500:
+		return target == ErrServerError
+	}
+	return false
+}
+
 func (e *APIError) invalidToken() string {
 	return fmt.Sprintf(
 		"gofactory api error: invalid token for the Satisfactory API: %s",
@@ -27,6 +205,15 @@ func (e *APIError) invalidToken() string {
 	)
 }
 
+func (e *APIError) httpStatusMessage() string {
+	if len(e.Message) == 0 {
+		return fmt.Sprintf("gofactory api error | http status: %d %s\n",
+			e.HTTPStatusCode, http.StatusText(e.HTTPStatusCode))
+	}
+	return fmt.Sprintf("gofactory api error | http status: %d %s | message: %s\n",
+		e.HTTPStatusCode, http.StatusText(e.HTTPStatusCode), e.Message)
+}
+
 func (e *APIError) defaultMessage() string {
 	if e.Data != nil {
 		dataStr, err := e.marshalErrorData()
@@ -51,3 +238,48 @@ func (e *APIError) marshalErrorData() (string, error) {
 	}
 	return string(b), nil
 }
+
+// maxErrorBodySize limits how much of an error response body is read into an APIError.
+const maxErrorBodySize = 64 << 10
+
+// decodeAPIError builds an *APIError from a response with an error status to a call of function.
+// Bodies that are empty or not a JSON error are kept as the message instead of failing to decode.
+func decodeAPIError(function string, resp *http.Response) error {
+	apiError := &APIError{HTTPStatusCode: resp.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return &TransportError{Function: function, Err: err}
+	}
+
+	if json.Unmarshal(body, apiError) != nil || len(apiError.StatusCode) == 0 {
+		apiError.StatusCode = ""
+		apiError.Message = strings.TrimSpace(string(body))
+	}
+
+	return apiError
+}
+
+// TransportError is returned when an API call fails before the server
+// sends a response, such as a refused connection or a timeout.
+type TransportError struct {
+	// Function is the API function that was being called.
+	Function string
+
+	// Err is the underlying error returned by the HTTP client.
+	Err error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("gofactory transport error | function: %s | %s", e.Function, e.Err)
+}
+
+// Is reports whether target is ErrTransport.
+func (e *TransportError) Is(target error) bool {
+	return target == ErrTransport
+}
+
+// Unwrap returns the underlying error, so the cause can be matched with errors.Is and errors.As.
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
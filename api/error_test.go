@@ -0,0 +1,186 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+func TestAPIErrors(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	token := server.Claim("Test", "admin")
+	admin := server.NewClient(token)
+
+	closed := apitest.NewServer()
+	closed.Close()
+
+	revoked := server.IssueToken(api.ADMINISTRATOR_PRIVILEGE)
+	server.RevokeToken(revoked)
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		call func() error
+
+		// wantErr is the sentinel of the error code, and wantStatus the sentinel of the HTTP status.
+		wantErr    error
+		wantStatus error
+		wantCode   string
+	}{
+		{
+			name: "wrong password",
+			call: func() error {
+				return server.NewClient("").PasswordLogin(context.Background(), api.ADMINISTRATOR_PRIVILEGE, "wrong")
+			},
+			wantErr:    api.ErrWrongPassword,
+			wantStatus: api.ErrUnauthorized,
+			wantCode:   api.ErrorCodeWrongPassword,
+		},
+		{
+			name: "revoked token",
+			call: func() error {
+				_, err := server.NewClient(revoked).QueryServerState(context.Background())
+				return err
+			},
+			wantErr:    api.ErrInvalidToken,
+			wantStatus: api.ErrUnauthorized,
+			wantCode:   api.ErrorCodeInvalidToken,
+		},
+		{
+			name: "insufficient scope",
+			call: func() error {
+				return server.NewClient(server.IssueToken(api.CLIENT_PRIVILEGE)).ShutdownServer(context.Background())
+			},
+			wantErr:    api.ErrInsufficientScope,
+			wantStatus: api.ErrForbidden,
+			wantCode:   api.ErrorCodeInsufficientScope,
+		},
+		{
+			name: "passwordless login on a claimed server",
+			call: func() error {
+				return server.NewClient("").PasswordlessLogin(context.Background(), api.ADMINISTRATOR_PRIVILEGE)
+			},
+			wantErr:    api.ErrPasswordlessLoginNotPossible,
+			wantStatus: api.ErrForbidden,
+			wantCode:   api.ErrorCodePasswordlessLoginNotPossible,
+		},
+		{
+			name:       "password in use",
+			call:       func() error { return admin.SetClientPassword(context.Background(), "admin") },
+			wantErr:    api.ErrPasswordInUse,
+			wantStatus: api.ErrBadRequest,
+			wantCode:   api.ErrorCodePasswordInUse,
+		},
+		{
+			name:       "missing save",
+			call:       func() error { return admin.LoadGame(context.Background(), "missing", false) },
+			wantErr:    api.ErrFileNotFound,
+			wantStatus: api.ErrNotFound,
+			wantCode:   api.ErrorCodeFileNotFound,
+		},
+		{
+			name: "no active session",
+			call: func() error {
+				return admin.ApplyAdvancedGameSettings(context.Background(), api.AdvancedGameSettings{NoPower: "True"})
+			},
+			wantErr:    api.ErrNoActiveSession,
+			wantStatus: api.ErrBadRequest,
+			wantCode:   api.ErrorCodeNoActiveSession,
+		},
+		{
+			name: "unknown function",
+			call: func() error {
+				_, err := api.CallFunction[api.Empty, api.Empty](context.Background(), admin, "NoSuchFunction", api.Empty{})
+				return err
+			},
+			wantErr:    api.ErrUnknownFunction,
+			wantStatus: api.ErrNotFound,
+			wantCode:   api.ErrorCodeUnknownFunction,
+		},
+		{
+			name: "server error without a code",
+			call: func() error {
+				server.FailNext(1, http.StatusBadGateway)
+				return admin.RenameServer(context.Background(), "Renamed")
+			},
+			wantStatus: api.ErrServerError,
+			wantCode:   "http_502",
+		},
+		{
+			name: "unreachable server",
+			call: func() error {
+				_, err := closed.NewClient(token).QueryServerState(context.Background())
+				return err
+			},
+			wantStatus: api.ErrTransport,
+			wantCode:   api.ErrorCodeTransport,
+		},
+		{
+			name: "canceled context",
+			call: func() error {
+				_, err := admin.QueryServerState(canceled)
+				return err
+			},
+			wantStatus: context.Canceled,
+			wantCode:   api.ErrorCodeCanceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			if err == nil {
+				t.Fatal("call error = nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("call error = %v, want %v", err, tt.wantErr)
+			}
+			if !errors.Is(err, tt.wantStatus) {
+				t.Errorf("call error = %v, want %v", err, tt.wantStatus)
+			}
+			if got := api.ErrorCodeOf(err); got != tt.wantCode {
+				t.Errorf("ErrorCodeOf() = %q, want %q", got, tt.wantCode)
+			}
+
+			var apiError *api.APIError
+			if errors.As(err, &apiError) && apiError.StatusCode != "" && len(apiError.Message) == 0 {
+				t.Errorf("APIError %q has no message", apiError.StatusCode)
+			}
+		})
+	}
+}
+
+func TestAPIErrorWithoutRequest(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	// A custom transport may return a response without its request, and with a body that fails to read.
+	brokenResponse := func(http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusInternalServerError,
+				Header:     make(http.Header),
+				Body:       io.NopCloser(&failingReader{reader: strings.NewReader(""), err: errors.New("connection reset")}),
+			}, nil
+		})
+	}
+	client := server.NewClient(server.Claim("Test", "admin"), api.WithMiddleware(brokenResponse))
+
+	_, err := client.QueryServerState(context.Background())
+	var transportError *api.TransportError
+	if !errors.As(err, &transportError) || transportError.Function != api.QueryServerStateFunction {
+		t.Fatalf("QueryServerState() error = %v, want a transport error of %s", err, api.QueryServerStateFunction)
+	}
+	if !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("QueryServerState() error = %v, want the read error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// jsonKeys returns the JSON keys of the fields of the struct type t.
+func jsonKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool)
+	for i := range t.NumField() {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if len(name) != 0 && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
+
+// marshalWithExtra marshals known, a struct of string options, as a JSON object, adding the extra
+// options whose keys are not already set by known.
+func marshalWithExtra(known any, extra map[string]string) ([]byte, error) {
+	data, err := json.Marshal(known)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	options := make(map[string]string)
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		if _, ok := options[key]; !ok {
+			options[key] = value
+		}
+	}
+	return json.Marshal(options)
+}
+
+// unmarshalExtra returns the options of the JSON object data whose keys are not in known, or nil if
+// there are none. Values that are not JSON strings are kept as their JSON text.
+func unmarshalExtra(data []byte, known map[string]bool) (map[string]string, error) {
+	var options map[string]json.RawMessage
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, err
+	}
+
+	var extra map[string]string
+	for key, raw := range options {
+		if known[key] {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]string)
+		}
+
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+		extra[key] = value
+	}
+	return extra, nil
+}
@@ -1,8 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
 )
 
 // genericFunctionBody represents a simple JSON body containing
@@ -11,19 +12,72 @@ type genericFunctionBody struct {
 	Function string `json:"function"`
 }
 
-// CreateGenericFunctionBody marshals the specified function name
+// MarshalGenericFunctionBody marshals the specified function name
 // into a JSON-encoded body for API requests. Specifically used
 // with POST requests that require no additional parameters.
-func CreateGenericFunctionBody(function string) []byte {
+func MarshalGenericFunctionBody(function string) ([]byte, error) {
 	body, err := json.Marshal(genericFunctionBody{
 		Function: function,
 	})
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("cannot marshal %s function body: %w", function, err)
 	}
+	return body, nil
+}
+
+// CreateGenericFunctionBody marshals the specified function name
+// into a JSON-encoded body for API requests. It returns nil if the
+// body cannot be marshalled, instead of exiting the process.
+//
+// Deprecated: Use MarshalGenericFunctionBody, which returns the error.
+func CreateGenericFunctionBody(function string) []byte {
+	body, _ := MarshalGenericFunctionBody(function)
 	return body
 }
 
+// Request is the {function, data} envelope every API function is called with.
+type Request[T any] struct {
+	// Function is the name of the API function to call.
+	Function string `json:"function"`
+
+	// Data holds the parameters of the function.
+	Data T `json:"data"`
+}
+
+// Response is the {data} envelope the server wraps the result of an API function in.
+type Response[T any] struct {
+	// Data holds the result of the function.
+	Data T `json:"data"`
+}
+
+// Empty is the request or response data of API functions that take no parameters or return no result.
+// Functions called with Empty data are sent without a data object.
+type Empty struct{}
+
+// CallFunction calls the API function with the given request data, and returns the data of its response
+// decoded into Resp. It works with any function, including custom or modded ones. Functions that return
+// no content give the zero value of Resp. Errors are returned as described in SendPostRequest.
+// It is not named Call, as Call is the type interceptors receive for every API call.
+//
+//	state, err := api.CallFunction[api.Empty, MyModState](ctx, client, "MyMod.GetState", api.Empty{})
+func CallFunction[Req, Resp any](ctx context.Context, c *GoFactoryClient, function string, data Req) (*Resp, error) {
+	var envelope any = Request[Req]{Function: function, Data: data}
+	if _, ok := any(data).(Empty); ok {
+		envelope = genericFunctionBody{Function: function}
+	}
+
+	functionBody, err := json.Marshal(envelope)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal %s function body: %w", function, err)
+	}
+
+	response, err := CreateAndSendPostRequest[Response[Resp]](ctx, c, function, functionBody)
+	if err != nil {
+		return nil, err
+	}
+	return &response.Data, nil
+}
+
 // String constants representing API function names used by the Satisfactory dedicated server API.
 const (
 	// HealthCheckFunction is the API function name for the server health check.
@@ -2,7 +2,6 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 )
 
@@ -48,21 +47,6 @@ func (c *GoFactoryClient) GetServerHealth(ctx context.Context, customData string
 		return nil, fmt.Errorf("must specify custom data for healthcheck")
 	}
 
-	functionBody, err := json.Marshal(HealthCheckRequest{
-		Function: HealthCheckFunction,
-		Data: HealthCheckCustomData{
-			CustomData: customData,
-		},
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	healthCheckResponse, err := CreateAndSendPostRequest[HealthCheckResponseData](ctx, c, HealthCheckFunction, functionBody)
-	if err != nil {
-		return nil, err
-	}
-
-	return &healthCheckResponse.Data, nil
+	return CallFunction[HealthCheckCustomData, HealthCheckResponse](ctx, c, HealthCheckFunction,
+		HealthCheckCustomData{CustomData: customData})
 }
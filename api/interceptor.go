@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Call describes a single API call made by a GoFactoryClient. It is passed to every Interceptor,
+// which sees the request fields before calling the next Invoker and the response fields after.
+type Call struct {
+	// Function is the name of the API function being called, such as QueryServerState.
+	Function string
+
+	// Privilege is the privilege level the client believes its token has.
+	Privilege Privilege
+
+	// Request is the HTTP request sent for the first attempt. An interceptor may replace it, such as with
+	// a clone carrying extra headers, before calling the next Invoker; retries are sent from the replacement.
+	Request *http.Request
+
+	// RequestBody is a copy of the request body. It is nil for bodies that are streamed,
+	// such as save game uploads, and may contain passwords and tokens.
+	RequestBody []byte
+
+	// RequestBytes is the number of bytes of the request body sent in the last attempt, including
+	// streamed bodies whose size is not known in advance.
+	RequestBytes int64
+
+	// ResponseBytes is the number of bytes read from the body of the last response.
+	ResponseBytes int64
+
+	// StatusCode is the HTTP status of the last response, or 0 if no response was received.
+	StatusCode int
+
+	// Attempts is the number of times the request was sent, which is more than 1 when it was retried.
+	Attempts int
+
+	// Latency is the time spent sending the request and decoding the response, including retries.
+	Latency time.Duration
+
+	// Err is the error returned by the call, such as an *APIError or a *TransportError.
+	Err error
+}
+
+// Invoker performs an API call.
+type Invoker func(ctx context.Context, call *Call) error
+
+// Interceptor wraps every API call made by a GoFactoryClient. It must call next to perform the call,
+// and may inspect or change the context and call before it, and the call and error after it.
+// Interceptors are used for audit logging, metrics, tracing and redaction.
+type Interceptor func(ctx context.Context, call *Call, next Invoker) error
+
+// Middleware wraps the http.RoundTripper used by a GoFactoryClient.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// WithInterceptors adds interceptors to the client. The first interceptor is the outermost one.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(o *clientOptions) {
+		o.interceptors = append(o.interceptors, interceptors...)
+	}
+}
+
+// WithMiddleware wraps the client's transport in the given middleware. The first middleware
+// is the outermost one, and sees each request first.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(o *clientOptions) {
+		o.middleware = append(o.middleware, middleware...)
+	}
+}
+
+// Use adds interceptors to the client, after any that were already added.
+// Calls already in progress are not affected.
+func (c *GoFactoryClient) Use(interceptors ...Interceptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interceptors = append(slices.Clone(c.interceptors), interceptors...)
+}
+
+// newCall creates the Call for an API request, copying the request body if it can be read again.
+func (c *GoFactoryClient) newCall(request *http.Request) *Call {
+	call := &Call{
+		Function:  request.URL.Query().Get("function"),
+		Privilege: c.Privilege(),
+		Request:   request,
+	}
+
+	if request.GetBody != nil {
+		if body, err := request.GetBody(); err == nil {
+			call.RequestBody, _ = io.ReadAll(body)
+			_ = body.Close()
+		}
+	}
+
+	return call
+}
+
+// invoke runs invoker for the call through the client's interceptors, and fills in
+// the latency and error of the call.
+func (c *GoFactoryClient) invoke(ctx context.Context, call *Call, invoker Invoker) error {
+	chain := func(ctx context.Context, call *Call) error {
+		start := time.Now()
+		err := invoker(ctx, call)
+		call.Latency = time.Since(start)
+		call.Err = err
+		return err
+	}
+
+	c.mu.RLock()
+	interceptors := c.interceptors
+	c.mu.RUnlock()
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], chain
+		chain = func(ctx context.Context, call *Call) error {
+			return interceptor(ctx, call, next)
+		}
+	}
+
+	return chain(ctx, call)
+}
+
+// wrapTransport applies middleware to transport, the first middleware being the outermost.
+func wrapTransport(transport http.RoundTripper, middleware []Middleware) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		transport = middleware[i](transport)
+	}
+	return transport
+}
+
+// countingReader counts the bytes read from reader into count.
+type countingReader struct {
+	reader io.Reader
+	count  *int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	*r.count += int64(n)
+	return n, err
+}
+
+// countingBody counts the bytes of a request body read by the transport. The count is atomic, as the
+// transport may still be sending the body when the response arrives.
+type countingBody struct {
+	body  io.ReadCloser
+	count atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.count.Add(int64(n))
+	return n, err
+}
+
+func (b *countingBody) Close() error {
+	return b.body.Close()
+}
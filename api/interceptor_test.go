@@ -0,0 +1,158 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+// headerRecorder is a middleware recording a header of every request sent by a client.
+type headerRecorder struct {
+	name string
+
+	mu     sync.Mutex
+	values []string
+}
+
+func (r *headerRecorder) middleware(next http.RoundTripper) http.RoundTripper {
+	return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		r.mu.Lock()
+		r.values = append(r.values, request.Header.Get(r.name))
+		r.mu.Unlock()
+		return next.RoundTrip(request)
+	})
+}
+
+func (r *headerRecorder) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.values)
+}
+
+func TestInterceptorsOrderAndCall(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	token := server.Claim("Test", "admin")
+
+	var order []string
+	var calls []api.Call
+	recording := func(name string) api.Interceptor {
+		return func(ctx context.Context, call *api.Call, next api.Invoker) error {
+			order = append(order, name+" before")
+			err := next(ctx, call)
+			order = append(order, name+" after")
+			if name == "inner" {
+				calls = append(calls, *call)
+			}
+			return err
+		}
+	}
+
+	client := server.NewClient(token, api.WithInterceptors(recording("outer"), recording("inner")))
+	if _, err := client.QueryServerState(context.Background()); err != nil {
+		t.Fatalf("QueryServerState() error = %v", err)
+	}
+	if err := client.LoadGame(context.Background(), "missing", false); !errors.Is(err, api.ErrFileNotFound) {
+		t.Fatalf("LoadGame() error = %v, want %v", err, api.ErrFileNotFound)
+	}
+
+	wantOrder := []string{"outer before", "inner before", "inner after", "outer after"}
+	if !slices.Equal(order[:4], wantOrder) {
+		t.Errorf("interceptor order = %v, want %v", order[:4], wantOrder)
+	}
+
+	tests := []struct {
+		function   string
+		statusCode int
+		wantErr    error
+	}{
+		{api.QueryServerStateFunction, http.StatusOK, nil},
+		{api.LoadGameFunction, http.StatusNotFound, api.ErrFileNotFound},
+	}
+	for i, tt := range tests {
+		call := calls[i]
+		if call.Function != tt.function || call.StatusCode != tt.statusCode || call.Attempts != 1 || !errors.Is(call.Err, tt.wantErr) {
+			t.Errorf("call %d = {%s %d attempts %d err %v}, want {%s %d attempts 1 err %v}", i,
+				call.Function, call.StatusCode, call.Attempts, call.Err, tt.function, tt.statusCode, tt.wantErr)
+		}
+		if call.Privilege != api.ADMINISTRATOR_PRIVILEGE || call.Request == nil || len(call.RequestBody) == 0 {
+			t.Errorf("call %d has privilege %s, request %v and body %q", i, call.Privilege, call.Request, call.RequestBody)
+		}
+	}
+	if calls[0].ResponseBytes == 0 {
+		t.Error("QueryServerState call recorded no response bytes")
+	}
+}
+
+func TestInterceptorReplacesRequest(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	token := server.Claim("Test", "admin")
+
+	addHeader := func(ctx context.Context, call *api.Call, next api.Invoker) error {
+		call.Request = call.Request.Clone(ctx)
+		call.Request.Header.Set("X-Audit", "interceptor")
+		return next(ctx, call)
+	}
+
+	tests := []struct {
+		name      string
+		failures  int
+		wantSends int
+		call      func(client *api.GoFactoryClient) error
+	}{
+		{
+			name:      "single attempt",
+			wantSends: 1,
+			call: func(client *api.GoFactoryClient) error {
+				_, err := client.QueryServerState(context.Background())
+				return err
+			},
+		},
+		{
+			name:      "retried attempts",
+			failures:  2,
+			wantSends: 3,
+			call: func(client *api.GoFactoryClient) error {
+				_, err := client.QueryServerState(context.Background())
+				return err
+			},
+		},
+		{
+			name:      "download",
+			wantSends: 1,
+			call: func(client *api.GoFactoryClient) error {
+				server.AddSave("Session", "Save", []byte("save"))
+				_, err := client.DownloadSaveGame(context.Background(), "Save")
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &headerRecorder{name: "X-Audit"}
+			client := server.NewClient(token,
+				api.WithInterceptors(addHeader),
+				api.WithMiddleware(recorder.middleware),
+				api.WithRetryPolicy(&api.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+			server.FailNext(tt.failures, http.StatusServiceUnavailable)
+
+			if err := tt.call(client); err != nil {
+				t.Fatalf("call error = %v", err)
+			}
+
+			want := slices.Repeat([]string{"interceptor"}, tt.wantSends)
+			if got := recorder.recorded(); !slices.Equal(got, want) {
+				t.Errorf("X-Audit headers sent = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -32,7 +32,7 @@ func CheckMagicPacket(r *bytes.Reader) error {
 	var magic uint16
 	err := binary.Read(r, binary.LittleEndian, &magic)
 	if err != nil {
-		return nil
+		return fmt.Errorf("cannot read magic packet: %w", err)
 	}
 	if magic != ProtocolMagic {
 		return fmt.Errorf("invalid magic packet, expected %v, got %v", ProtocolMagic, magic)
@@ -44,7 +44,7 @@ func CheckMessageType(r *bytes.Reader, t uint8) error {
 	var messageType uint8
 	err := binary.Read(r, binary.LittleEndian, &messageType)
 	if err != nil {
-		return nil
+		return fmt.Errorf("cannot read message type: %w", err)
 	}
 	if messageType != t {
 		return fmt.Errorf("invalid message type, expected %v, got %v", t, messageType)
@@ -56,7 +56,7 @@ func CheckVersion(r *bytes.Reader) error {
 	var version uint8
 	err := binary.Read(r, binary.LittleEndian, &version)
 	if err != nil {
-		return err
+		return fmt.Errorf("cannot read protocol version: %w", err)
 	}
 	if version != ProtocolVersion {
 		return fmt.Errorf("invalid protocol version, expected %v, got %v", ProtocolVersion, version)
@@ -106,7 +106,7 @@ func SendUDPQuery(server string, request []byte, maxRetries int, retryDelay time
 			return nil, err
 		}
 
-		err := conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		err = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
 		if err != nil {
 			return nil, err
 		}
@@ -119,7 +119,10 @@ func SendUDPQuery(server string, request []byte, maxRetries int, retryDelay time
 		time.Sleep(retryDelay)
 	}
 
-	return nil, fmt.Errorf("no response after %d retries", maxRetries)
+	if err == nil {
+		return nil, fmt.Errorf("no response after %d retries", maxRetries)
+	}
+	return nil, fmt.Errorf("no response after %d retries: %w", maxRetries, err)
 }
 
 type ServerState int
@@ -0,0 +1,208 @@
+// Package metrics provides an optional Prometheus collector for GoFactoryClient calls and lightweight UDP queries.
+//
+// Register a Collector with a Prometheus registry, then instrument each client with it:
+//
+//	collector := metrics.NewCollector()
+//	prometheus.MustRegister(collector)
+//
+//	client := api.NewClient(url, api.WithAuthToken(token), api.WithInterceptors(collector.Interceptor()))
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Directions used for the transferred bytes metric.
+const (
+	DirectionUpload   = "upload"
+	DirectionDownload = "download"
+)
+
+// Collector records metrics for GoFactoryClient calls and UDP queries. It implements prometheus.Collector
+// and is safe for concurrent use, so one Collector can instrument the clients of several servers.
+type Collector struct {
+	requests    *prometheus.CounterVec
+	errors      *prometheus.CounterVec
+	latency     *prometheus.HistogramVec
+	transferred *prometheus.CounterVec
+
+	udpQueries *prometheus.CounterVec
+	udpErrors  *prometheus.CounterVec
+	udpLatency *prometheus.HistogramVec
+}
+
+// Option configures a Collector created with NewCollector.
+type Option func(*collectorOptions)
+
+// collectorOptions collects the settings applied by each Option before the collector is built.
+type collectorOptions struct {
+	namespace   string
+	constLabels prometheus.Labels
+	buckets     []float64
+}
+
+// WithNamespace sets the prefix of every metric name. Defaults to "gofactory".
+func WithNamespace(namespace string) Option {
+	return func(o *collectorOptions) {
+		o.namespace = namespace
+	}
+}
+
+// WithConstLabels adds labels with fixed values to every metric, such as the environment.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(o *collectorOptions) {
+		o.constLabels = labels
+	}
+}
+
+// WithBuckets sets the buckets, in seconds, of the latency histograms. Defaults to prometheus.DefBuckets.
+func WithBuckets(buckets []float64) Option {
+	return func(o *collectorOptions) {
+		o.buckets = buckets
+	}
+}
+
+// NewCollector creates a Collector configured with the given options.
+func NewCollector(opts ...Option) *Collector {
+	o := collectorOptions{
+		namespace: "gofactory",
+		buckets:   prometheus.DefBuckets,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Collector{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "api",
+			Name:        "requests_total",
+			Help:        "Number of HTTPS API calls, by server and function.",
+			ConstLabels: o.constLabels,
+		}, []string{"server", "function"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "api",
+			Name:        "errors_total",
+			Help:        "Number of failed HTTPS API calls, by server, function and error code.",
+			ConstLabels: o.constLabels,
+		}, []string{"server", "function", "error_code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "api",
+			Name:        "request_duration_seconds",
+			Help:        "Duration of HTTPS API calls including retries, by server and function.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, []string{"server", "function"}),
+		transferred: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "api",
+			Name:        "save_transferred_bytes_total",
+			Help:        "Bytes of save games uploaded and downloaded, by server and direction.",
+			ConstLabels: o.constLabels,
+		}, []string{"server", "direction"}),
+		udpQueries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "udp",
+			Name:        "queries_total",
+			Help:        "Number of lightweight UDP queries, by server.",
+			ConstLabels: o.constLabels,
+		}, []string{"server"}),
+		udpErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "udp",
+			Name:        "query_errors_total",
+			Help:        "Number of lightweight UDP queries that got no response, by server.",
+			ConstLabels: o.constLabels,
+		}, []string{"server"}),
+		udpLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   o.namespace,
+			Subsystem:   "udp",
+			Name:        "query_duration_seconds",
+			Help:        "Duration of lightweight UDP queries including retries, by server.",
+			ConstLabels: o.constLabels,
+			Buckets:     o.buckets,
+		}, []string{"server"}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	c.requests.Describe(ch)
+	c.errors.Describe(ch)
+	c.latency.Describe(ch)
+	c.transferred.Describe(ch)
+	c.udpQueries.Describe(ch)
+	c.udpErrors.Describe(ch)
+	c.udpLatency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.requests.Collect(ch)
+	c.errors.Collect(ch)
+	c.latency.Collect(ch)
+	c.transferred.Collect(ch)
+	c.udpQueries.Collect(ch)
+	c.udpErrors.Collect(ch)
+	c.udpLatency.Collect(ch)
+}
+
+// Interceptor returns an api.Interceptor that records every call made by a client.
+func (c *Collector) Interceptor() api.Interceptor {
+	return func(ctx context.Context, call *api.Call, next api.Invoker) error {
+		err := next(ctx, call)
+
+		server := serverLabel(call)
+		c.requests.WithLabelValues(server, call.Function).Inc()
+		c.latency.WithLabelValues(server, call.Function).Observe(call.Latency.Seconds())
+		if err != nil {
+			c.errors.WithLabelValues(server, call.Function, api.ErrorCodeOf(err)).Inc()
+		}
+
+		switch call.Function {
+		case api.UploadSaveGameFunction:
+			if call.RequestBytes > 0 {
+				c.transferred.WithLabelValues(server, DirectionUpload).Add(float64(call.RequestBytes))
+			}
+		case api.DownloadSaveGameFunction:
+			if call.ResponseBytes > 0 {
+				c.transferred.WithLabelValues(server, DirectionDownload).Add(float64(call.ResponseBytes))
+			}
+		}
+
+		return err
+	}
+}
+
+// Instrument adds the collector's interceptor to client.
+func (c *Collector) Instrument(client *api.GoFactoryClient) {
+	client.Use(c.Interceptor())
+}
+
+// SendUDPQuery calls api.SendUDPQuery and records the query.
+func (c *Collector) SendUDPQuery(server string, request []byte, maxRetries int, retryDelay time.Duration) ([]byte, error) {
+	start := time.Now()
+	response, err := api.SendUDPQuery(server, request, maxRetries, retryDelay)
+
+	c.udpQueries.WithLabelValues(server).Inc()
+	c.udpLatency.WithLabelValues(server).Observe(time.Since(start).Seconds())
+	if err != nil {
+		c.udpErrors.WithLabelValues(server).Inc()
+	}
+
+	return response, err
+}
+
+// serverLabel returns the host:port the call was sent to.
+func serverLabel(call *api.Call) string {
+	if call.Request == nil || call.Request.URL == nil {
+		return ""
+	}
+	return call.Request.URL.Host
+}
@@ -0,0 +1,86 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+	"github.com/alchemicalkube/gofactory/api/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// counterValue returns the value of the counter with the given name and label values, or 0 if it was never set.
+func counterValue(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if want, ok := labels[label.GetName()]; ok && label.GetValue() != want {
+					continue metrics
+				}
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestCollectorTransferredBytes(t *testing.T) {
+	save := bytes.Repeat([]byte("factory"), 1024)
+
+	tests := []struct {
+		name string
+		file func() io.Reader
+	}{
+		{name: "known size", file: func() io.Reader { return bytes.NewReader(save) }},
+		// A reader of unknown size is sent chunked, without a Content-Length.
+		{name: "unknown size", file: func() io.Reader { return io.MultiReader(bytes.NewReader(save)) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			token := server.Claim("Test", "admin")
+
+			collector := metrics.NewCollector()
+			registry := prometheus.NewRegistry()
+			registry.MustRegister(collector)
+			client := server.NewClient(token, api.WithInterceptors(collector.Interceptor()))
+			ctx := context.Background()
+
+			err := client.UploadSaveGame(ctx, tt.file(), "Save.sav", api.UploadSaveGameDataRequest{SaveName: "Save"})
+			if err != nil {
+				t.Fatalf("UploadSaveGame() error = %v", err)
+			}
+			if _, err := client.DownloadSaveGame(ctx, "Save"); err != nil {
+				t.Fatalf("DownloadSaveGame() error = %v", err)
+			}
+
+			name := "gofactory_api_save_transferred_bytes_total"
+			// The upload also counts the multipart headers and the JSON data part.
+			if got := counterValue(t, registry, name, map[string]string{"direction": metrics.DirectionUpload}); got <= float64(len(save)) {
+				t.Errorf("uploaded bytes = %v, want more than the %d bytes of the save", got, len(save))
+			}
+			if got := counterValue(t, registry, name, map[string]string{"direction": metrics.DirectionDownload}); got != float64(len(save)) {
+				t.Errorf("downloaded bytes = %v, want %d", got, len(save))
+			}
+			got := counterValue(t, registry, "gofactory_api_requests_total", map[string]string{"function": api.UploadSaveGameFunction})
+			if got != 1 {
+				t.Errorf("upload requests = %v, want 1", got)
+			}
+		})
+	}
+}
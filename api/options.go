@@ -0,0 +1,237 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultUserAgent is the User-Agent header sent by clients that do not set their own.
+const DefaultUserAgent = "gofactory/" + Version
+
+// Option configures a GoFactoryClient created with NewClient.
+type Option func(*clientOptions)
+
+// clientOptions collects the settings applied by each Option before the client is built.
+type clientOptions struct {
+	token              string
+	timeout            time.Duration
+	userAgent          string
+	headers            http.Header
+	httpClient         *http.Client
+	transport          http.RoundTripper
+	proxy              func(*http.Request) (*url.URL, error)
+	rootCAs            *x509.CertPool
+	insecureSkipVerify bool
+	pins               []string
+	knownHosts         *KnownHosts
+	confirmFingerprint ConfirmFingerprint
+	retryPolicy        *RetryPolicy
+	interceptors       []Interceptor
+	middleware         []Middleware
+	credentials        CredentialProvider
+	preflight          bool
+	commandPolicy      *CommandPolicy
+
+	// host is the host:port of the server, used to look up its certificate fingerprint.
+	host string
+}
+
+// WithAuthToken sets the authentication token sent with every API request.
+func WithAuthToken(token string) Option {
+	return func(o *clientOptions) {
+		o.token = token
+	}
+}
+
+// WithTimeout sets the time limit for each HTTP request, including reading the response body.
+// Zero means no timeout, which is the default.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *clientOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every API request. Defaults to DefaultUserAgent.
+func WithUserAgent(userAgent string) Option {
+	return func(o *clientOptions) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithHeader adds a header sent with every API request. It can be used more than once,
+// including for the same header name.
+func WithHeader(name string, value string) Option {
+	return func(o *clientOptions) {
+		o.headers.Add(name, value)
+	}
+}
+
+// WithDefaultHeaders adds every header in the map to all API requests.
+func WithDefaultHeaders(headers map[string]string) Option {
+	return func(o *clientOptions) {
+		for name, value := range headers {
+			o.headers.Add(name, value)
+		}
+	}
+}
+
+// WithHTTPClient uses the given *http.Client for API requests instead of building one.
+// The timeout, transport, proxy and TLS options are applied on top of it.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *clientOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithTransport sets the http.RoundTripper used for API requests. The proxy and TLS options
+// only configure the default transport, so they have no effect when a custom transport is set,
+// except for certificate pins and trust on first use, which make every call fail with
+// ErrUnverifiableTransport rather than connect without checking the certificate.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
+}
+
+// WithProxy sets the function that selects the proxy for each request, like http.Transport.Proxy.
+// The default transport uses the proxy from the environment, as http.ProxyFromEnvironment does.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(o *clientOptions) {
+		o.proxy = proxy
+	}
+}
+
+// WithProxyURL sends every request through the proxy at the given URL.
+func WithProxyURL(proxyURL *url.URL) Option {
+	return WithProxy(http.ProxyURL(proxyURL))
+}
+
+// WithRootCAs sets the certificate pool used to verify the server's certificate,
+// such as a pool holding the dedicated server's self-signed certificate.
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(o *clientOptions) {
+		o.rootCAs = pool
+	}
+}
+
+// WithInsecureSkipVerify disables verification of the server's certificate when set to true.
+func WithInsecureSkipVerify(skipVerify bool) Option {
+	return func(o *clientOptions) {
+		o.insecureSkipVerify = skipVerify
+	}
+}
+
+// WithRetryPolicy sets the policy used to retry idempotent API calls after a transient failure.
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(o *clientOptions) {
+		o.retryPolicy = policy
+	}
+}
+
+// NewClient creates a new GoFactoryClient for the dedicated server at serverURL, configured with the given options.
+func NewClient(serverURL string, opts ...Option) *GoFactoryClient {
+	o := clientOptions{
+		userAgent: DefaultUserAgent,
+		headers:   make(http.Header),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if parsed, err := url.Parse(serverURL); err == nil {
+		o.host = parsed.Host
+	}
+
+	client := &GoFactoryClient{
+		URL:              serverURL,
+		Client:           o.buildHTTPClient(),
+		RetryPolicy:      o.retryPolicy,
+		userAgent:        o.userAgent,
+		headers:          o.headers,
+		token:            o.token,
+		currentPrivilege: tokenPrivilege(o.token, API_TOKEN_PRIVILEGE),
+		interceptors:     o.interceptors,
+		credentials:      o.credentials,
+		preflight:        o.preflight,
+		commandPolicy:    o.commandPolicy,
+	}
+	return client
+}
+
+// buildHTTPClient creates the *http.Client described by the options.
+func (o *clientOptions) buildHTTPClient() *http.Client {
+	httpClient := &http.Client{}
+	if o.httpClient != nil {
+		copied := *o.httpClient
+		httpClient = &copied
+	}
+
+	if o.timeout > 0 {
+		httpClient.Timeout = o.timeout
+	}
+
+	switch {
+	case o.verifiesFingerprint() && len(o.host) == 0:
+		// Every server without a host would share the same known hosts entry and pins.
+		httpClient.Transport = unverifiableTransport{err: ErrMissingServerHost}
+	case o.transport != nil:
+		httpClient.Transport = o.transport
+		if o.verifiesFingerprint() {
+			httpClient.Transport = unverifiableTransport{err: ErrUnverifiableTransport}
+		}
+	case o.httpClient == nil || o.httpClient.Transport == nil:
+		httpClient.Transport = o.buildTransport(http.DefaultTransport.(*http.Transport).Clone())
+	default:
+		if transport, ok := httpClient.Transport.(*http.Transport); ok {
+			httpClient.Transport = o.buildTransport(transport.Clone())
+		} else if o.verifiesFingerprint() {
+			httpClient.Transport = unverifiableTransport{err: ErrUnverifiableTransport}
+		}
+	}
+
+	if len(o.middleware) > 0 {
+		httpClient.Transport = wrapTransport(httpClient.Transport, o.middleware)
+	}
+
+	return httpClient
+}
+
+// verifiesFingerprint reports whether the options check the server's certificate fingerprint,
+// which must never be skipped silently.
+func (o *clientOptions) verifiesFingerprint() bool {
+	return len(o.pins) > 0 || o.knownHosts != nil
+}
+
+// buildTransport applies the proxy and TLS options to the given transport.
+// Certificate pins and trust on first use take precedence over the other TLS options.
+func (o *clientOptions) buildTransport(transport *http.Transport) *http.Transport {
+	if o.proxy != nil {
+		transport.Proxy = o.proxy
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	if o.rootCAs != nil {
+		transport.TLSClientConfig.RootCAs = o.rootCAs
+	}
+	if o.insecureSkipVerify {
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+	if o.verifiesFingerprint() {
+		// The fingerprint replaces certificate authority verification, as dedicated servers
+		// present a self-signed certificate.
+		verifier := &certificateVerifier{
+			host:       o.host,
+			pins:       o.pins,
+			knownHosts: o.knownHosts,
+			confirm:    o.confirmFingerprint,
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = true
+		transport.TLSClientConfig.VerifyConnection = verifier.verifyConnection
+	}
+
+	return transport
+}
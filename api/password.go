@@ -2,25 +2,25 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 )
 
-// Privilege level constants used for authentication.
+// Privilege level constants used for authentication. They are untyped, so they can be used
+// both as a string and as a Privilege.
 const (
 	// NOT_AUTHENTICATED_PRIVILEGE represents a user who is not authenticated.
-	NOT_AUTHENTICATED_PRIVILEGE string = "NotAuthenticated"
+	NOT_AUTHENTICATED_PRIVILEGE = "NotAuthenticated"
 
 	// CLIENT_PRIVILEGE represents a standard client privilege level.
-	CLIENT_PRIVILEGE string = "Client"
+	CLIENT_PRIVILEGE = "Client"
 
 	// ADMINISTRATOR_PRIVILEGE represents administrator-level privileges.
-	ADMINISTRATOR_PRIVILEGE string = "Administrator"
+	ADMINISTRATOR_PRIVILEGE = "Administrator"
 
 	// INITIAL_ADMIN_PRIVILEGE represents the initial admin privilege level during claim.
-	INITIAL_ADMIN_PRIVILEGE string = "InitialAdmin"
+	INITIAL_ADMIN_PRIVILEGE = "InitialAdmin"
 
 	// API_TOKEN_PRIVILEGE represents an API token-based privilege level.
-	API_TOKEN_PRIVILEGE string = "ApiToken"
+	API_TOKEN_PRIVILEGE = "ApiToken"
 )
 
 // PasswordlessLoginRequest represents a request to authenticate using
@@ -74,58 +74,29 @@ type PasswordLoginResponseData struct {
 }
 
 // PasswordlessLogin authenticates the client using passwordless login on an unclaimed server or
-// when client protection password is not set. Updates the *GoFactoryClient.Token field with the new token.
+// when client protection password is not set. Updates the client's token with the new one.
 func (c *GoFactoryClient) PasswordlessLogin(ctx context.Context, privilege string) error {
-	functionBody, err := json.Marshal(PasswordlessLoginRequest{
-		Function: PasswordlessLoginFunction,
-		Data: PasswordlessLoginRequestData{
-			MinimumPrivilegeLevel: privilege,
-		},
-	})
+	response, err := CallFunction[PasswordlessLoginRequestData, PasswordLoginResponseData](ctx, c, PasswordlessLoginFunction,
+		PasswordlessLoginRequestData{MinimumPrivilegeLevel: privilege})
 	if err != nil {
 		return err
 	}
 
-	headers := map[string]string{
-		"Content-Type": "application/json",
-	}
-
-	tokenResponse, err := CreateAndSendPostRequestWithHeaders[LoginResponse](ctx, c, headers, PasswordlessLoginFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	c.currentPrivilege = privilege
-	c.Token = tokenResponse.Data.AuthToken
+	c.setSession(response.AuthToken, tokenPrivilege(response.AuthToken, privilege))
 
 	return nil
 }
 
 // PasswordLogin authenticates the client using a password for the specified privilege level.
-// Updates the *GoFactoryClient.Token field with the new token.
+// Updates the client's token with the new one.
 func (c *GoFactoryClient) PasswordLogin(ctx context.Context, privilege string, password string) error {
-	functionBody, err := json.Marshal(PasswordLoginRequest{
-		Function: PasswordLoginFunction,
-		Data: PasswordLoginRequestData{
-			MinimumPrivilegeLevel: privilege,
-			Password:              password,
-		},
-	})
-	if err != nil {
-		return err
-	}
-
-	headers := map[string]string{
-		"Content-Type": "application/json",
-	}
-
-	tokenResponse, err := CreateAndSendPostRequestWithHeaders[LoginResponse](ctx, c, headers, PasswordLoginFunction, functionBody)
+	response, err := CallFunction[PasswordLoginRequestData, PasswordLoginResponseData](ctx, c, PasswordLoginFunction,
+		PasswordLoginRequestData{MinimumPrivilegeLevel: privilege, Password: password})
 	if err != nil {
 		return err
 	}
 
-	c.currentPrivilege = privilege
-	c.Token = tokenResponse.Data.AuthToken
+	c.setSession(response.AuthToken, tokenPrivilege(response.AuthToken, privilege))
 	return nil
 }
 
@@ -140,34 +111,16 @@ type ClientPasswordRequest struct {
 
 // ClientPasswordRequestData holds the new client password.
 type ClientPasswordRequestData struct {
-	// Password is the new client password.
-	Password string `json:"password,omitempty"`
+	// Password is the new client password. It is always sent, as an empty password removes it.
+	Password string `json:"password"`
 }
 
 // SetClientPassword sets a new client password for the Satisfactory dedicated server.
+// An empty password removes the client password, so clients can log in without one.
 func (c *GoFactoryClient) SetClientPassword(ctx context.Context, newPassword string) error {
-	functionBody, err := json.Marshal(ClientPasswordRequest{
-		Function: SetClientPasswordFunction,
-		Data: ClientPasswordRequestData{
-			Password: newPassword,
-		},
-	})
-	if err != nil {
-		return err
-	}
-
-	req, err := c.CreatePostRequest(SetClientPasswordFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	var apiError APIError
-	err = c.SendPostRequest(ctx, req, &apiError)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := CallFunction[ClientPasswordRequestData, Empty](ctx, c, SetClientPasswordFunction,
+		ClientPasswordRequestData{Password: newPassword})
+	return err
 }
 
 // AdminPasswordRequest represents a request to set the administrator password.
@@ -198,24 +151,15 @@ type AdminPasswordResponse struct {
 }
 
 // SetAdminPassword sets a new administrator password on the Satisfactory server
-// and updates the *GoFactoryClient.Token with the new token.
+// and updates the client's token with the new Administrator token.
 // This POST requests invalidates all previous Client and Admin tokens.
 func (c *GoFactoryClient) SetAdminPassword(ctx context.Context, newPassword string) error {
-	functionBody, err := json.Marshal(AdminPasswordRequest{
-		Function: SetAdminPasswordFunction,
-		Data: AdminPasswordRequestData{
-			Password: newPassword,
-		},
-	})
-	if err != nil {
-		return err
-	}
-
-	resp, err := CreateAndSendPostRequest[AdminPasswordResponseData](ctx, c, SetAdminPasswordFunction, functionBody)
+	response, err := CallFunction[AdminPasswordRequestData, AdminPasswordResponse](ctx, c, SetAdminPasswordFunction,
+		AdminPasswordRequestData{Password: newPassword})
 	if err != nil {
 		return err
 	}
 
-	c.Token = resp.Data.AuthToken
+	c.setSession(response.AuthToken, tokenPrivilege(response.AuthToken, ADMINISTRATOR_PRIVILEGE))
 	return nil
 }
@@ -0,0 +1,84 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+func TestSetClientPassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantBody string
+
+		// wantPasswordless is whether clients can log in without a password afterwards.
+		wantPasswordless bool
+	}{
+		{
+			name:     "set password",
+			password: "client",
+			wantBody: `{"function":"SetClientPassword","data":{"password":"client"}}`,
+		},
+		{
+			name:             "remove password",
+			wantBody:         `{"function":"SetClientPassword","data":{"password":""}}`,
+			wantPasswordless: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			ctx := context.Background()
+
+			var mu sync.Mutex
+			var body []byte
+			recordBody := func(next http.RoundTripper) http.RoundTripper {
+				return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+					if request.URL.Query().Get("function") == api.SetClientPasswordFunction {
+						data, err := io.ReadAll(request.Body)
+						if err != nil {
+							return nil, err
+						}
+						mu.Lock()
+						body = data
+						mu.Unlock()
+						request.Body = io.NopCloser(bytes.NewReader(data))
+					}
+					return next.RoundTrip(request)
+				})
+			}
+			client := server.NewClient(server.Claim("Test", "admin"), api.WithMiddleware(recordBody))
+			if err := client.SetClientPassword(ctx, "previous"); err != nil {
+				t.Fatalf("SetClientPassword(previous) error = %v", err)
+			}
+
+			if err := client.SetClientPassword(ctx, tt.password); err != nil {
+				t.Fatalf("SetClientPassword() error = %v", err)
+			}
+			mu.Lock()
+			got := string(bytes.TrimSpace(body))
+			mu.Unlock()
+			if got != tt.wantBody {
+				t.Errorf("request body = %s, want %s", got, tt.wantBody)
+			}
+
+			err := server.NewClient("").PasswordlessLogin(ctx, api.CLIENT_PRIVILEGE)
+			if passwordless := err == nil; passwordless != tt.wantPasswordless {
+				t.Errorf("PasswordlessLogin() error = %v, want passwordless logins %v", err, tt.wantPasswordless)
+			}
+			if err != nil && !errors.Is(err, api.ErrPasswordlessLoginNotPossible) {
+				t.Errorf("PasswordlessLogin() error = %v, want %v", err, api.ErrPasswordlessLoginNotPossible)
+			}
+		})
+	}
+}
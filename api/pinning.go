@@ -0,0 +1,271 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrCertificateMismatch matches a *CertificateMismatchError with errors.Is.
+var ErrCertificateMismatch = errors.New("gofactory tls error: certificate fingerprint mismatch")
+
+// ErrUnverifiableTransport is returned by every call of a client configured with certificate pins or trust on
+// first use, when its transport is not an *http.Transport the certificate check could be added to.
+var ErrUnverifiableTransport = errors.New("gofactory tls error: certificate pins and trust on first use need an *http.Transport")
+
+// ErrMissingServerHost is returned by every call of a client configured with certificate pins or trust on
+// first use, when its server URL has no host to check the certificate of, such as a URL without a scheme.
+var ErrMissingServerHost = errors.New("gofactory tls error: certificate pins and trust on first use need a server URL with a host, such as https://host:7777")
+
+// ErrCertificateNotTrusted is returned when the server's certificate is seen for the
+// first time and the confirmation callback rejects it.
+var ErrCertificateNotTrusted = errors.New("gofactory tls error: certificate was not trusted")
+
+// CertificateMismatchError is returned when the server presents a certificate whose fingerprint
+// does not match the pinned or previously recorded one. This happens when the server's certificate
+// was regenerated, or when something is intercepting the connection.
+type CertificateMismatchError struct {
+	// Host is the host:port of the server.
+	Host string
+
+	// Expected are the fingerprints that would have been accepted.
+	Expected []string
+
+	// Got is the fingerprint of the certificate the server presented.
+	Got string
+}
+
+func (e *CertificateMismatchError) Error() string {
+	return fmt.Sprintf("gofactory tls error | certificate fingerprint mismatch for %s | expected: %s | got: %s",
+		e.Host, strings.Join(e.Expected, ", "), e.Got)
+}
+
+// Is reports whether target is ErrCertificateMismatch.
+func (e *CertificateMismatchError) Is(target error) bool {
+	return target == ErrCertificateMismatch
+}
+
+// CertificateFingerprint returns the SHA-256 fingerprint of a certificate as lowercase hex.
+func CertificateFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// FormatFingerprint formats a fingerprint as uppercase, colon separated byte pairs,
+// the way browsers and openssl display it.
+func FormatFingerprint(fingerprint string) string {
+	fingerprint = strings.ToUpper(NormalizeFingerprint(fingerprint))
+
+	pairs := make([]string, 0, len(fingerprint)/2)
+	for i := 0; i+1 < len(fingerprint); i += 2 {
+		pairs = append(pairs, fingerprint[i:i+2])
+	}
+	return strings.Join(pairs, ":")
+}
+
+// NormalizeFingerprint converts a SHA-256 fingerprint written as hex, with or without colons,
+// spaces or a "sha256:" prefix, to the lowercase hex form returned by CertificateFingerprint.
+func NormalizeFingerprint(fingerprint string) string {
+	fingerprint = strings.ToLower(strings.TrimSpace(fingerprint))
+	fingerprint = strings.TrimPrefix(fingerprint, "sha256:")
+	return strings.NewReplacer(":", "", " ", "", "-", "").Replace(fingerprint)
+}
+
+// KnownHosts is a known hosts file recording the certificate fingerprint of each server,
+// in the style of SSH. Each line holds a host:port and its fingerprint, separated by a space.
+// It is safe for concurrent use.
+type KnownHosts struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewKnownHosts returns a KnownHosts backed by the file at path. The file and its
+// directory are created when the first fingerprint is recorded.
+func NewKnownHosts(path string) *KnownHosts {
+	return &KnownHosts{path: path}
+}
+
+// DefaultKnownHostsPath returns the path of the known hosts file in the user's configuration directory.
+func DefaultKnownHostsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gofactory", "known_hosts"), nil
+}
+
+// Path returns the path of the known hosts file.
+func (k *KnownHosts) Path() string {
+	return k.path
+}
+
+// Lookup returns the recorded fingerprint for host and whether one was found.
+func (k *KnownHosts) Lookup(host string) (string, bool, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.lookup(host)
+}
+
+// lookup reads the file and finds the fingerprint for host. Must be called with k.mu held.
+func (k *KnownHosts) lookup(host string) (string, bool, error) {
+	f, err := os.Open(k.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("cannot open known hosts file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == host {
+			return NormalizeFingerprint(fields[1]), true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", false, fmt.Errorf("cannot read known hosts file: %w", err)
+	}
+	return "", false, nil
+}
+
+// Add records the fingerprint for host. It fails if a different fingerprint is already
+// recorded for host; remove the old line from the file to trust a new certificate.
+func (k *KnownHosts) Add(host string, fingerprint string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	fingerprint = NormalizeFingerprint(fingerprint)
+	known, found, err := k.lookup(host)
+	if err != nil {
+		return err
+	}
+	if found {
+		if known != fingerprint {
+			return &CertificateMismatchError{Host: host, Expected: []string{known}, Got: fingerprint}
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0o700); err != nil {
+		return fmt.Errorf("cannot create known hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(k.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("cannot open known hosts file: %w", err)
+	}
+	_, err = fmt.Fprintf(f, "%s %s\n", host, fingerprint)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("cannot write known hosts file: %w", err)
+	}
+	return nil
+}
+
+// ConfirmFingerprint is called the first time a server's certificate is seen when using
+// trust on first use. Returning true records the fingerprint in the known hosts file.
+type ConfirmFingerprint func(host string, fingerprint string) bool
+
+// WithCertificatePins only accepts a server certificate whose SHA-256 fingerprint is one of
+// the given fingerprints, instead of verifying it against certificate authorities.
+// Fingerprints are accepted in any form understood by NormalizeFingerprint. The check is added to
+// the client's *http.Transport; with a custom transport set through WithTransport, or a WithHTTPClient
+// transport of another type, every call fails with ErrUnverifiableTransport instead. If the server URL
+// has no host, every call fails with ErrMissingServerHost.
+func WithCertificatePins(fingerprints ...string) Option {
+	return func(o *clientOptions) {
+		for _, fingerprint := range fingerprints {
+			o.pins = append(o.pins, NormalizeFingerprint(fingerprint))
+		}
+	}
+}
+
+// WithTrustOnFirstUse verifies the server's certificate like SSH verifies host keys. The first
+// time a server is seen, confirm is asked whether to trust its fingerprint, which is then recorded
+// in knownHosts. Later connections are rejected with a *CertificateMismatchError if the fingerprint
+// changed. A nil confirm trusts every new server. Pins set with WithCertificatePins are also accepted.
+// Like WithCertificatePins, it fails every call with ErrUnverifiableTransport if the transport is not an *http.Transport,
+// and with ErrMissingServerHost if the server URL has no host.
+func WithTrustOnFirstUse(knownHosts *KnownHosts, confirm ConfirmFingerprint) Option {
+	return func(o *clientOptions) {
+		o.knownHosts = knownHosts
+		o.confirmFingerprint = confirm
+	}
+}
+
+// unverifiableTransport fails every request with err, in place of a transport the certificate check
+// could not be added to.
+type unverifiableTransport struct {
+	err error
+}
+
+func (t unverifiableTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		_ = request.Body.Close()
+	}
+	return nil, t.err
+}
+
+// certificateVerifier checks the certificate of a server against pins and a known hosts file.
+type certificateVerifier struct {
+	host       string
+	pins       []string
+	knownHosts *KnownHosts
+	confirm    ConfirmFingerprint
+
+	// mu serialises first use, so concurrent handshakes ask for confirmation only once.
+	mu sync.Mutex
+}
+
+// verifyConnection implements tls.Config.VerifyConnection.
+func (v *certificateVerifier) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("gofactory tls error: server presented no certificate")
+	}
+	fingerprint := CertificateFingerprint(state.PeerCertificates[0])
+
+	for _, pin := range v.pins {
+		if pin == fingerprint {
+			return nil
+		}
+	}
+	if v.knownHosts == nil {
+		return &CertificateMismatchError{Host: v.host, Expected: v.pins, Got: fingerprint}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	known, found, err := v.knownHosts.Lookup(v.host)
+	if err != nil {
+		return err
+	}
+	if found {
+		if known != fingerprint {
+			return &CertificateMismatchError{Host: v.host, Expected: append(slices.Clone(v.pins), known), Got: fingerprint}
+		}
+		return nil
+	}
+
+	if v.confirm != nil && !v.confirm(v.host, fingerprint) {
+		return ErrCertificateNotTrusted
+	}
+	return v.knownHosts.Add(v.host, fingerprint)
+}
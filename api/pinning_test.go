@@ -0,0 +1,182 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+// roundTripperFunc adapts a function to an http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestCertificatePins(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	fingerprint := api.CertificateFingerprint(server.Certificate())
+
+	tests := []struct {
+		name    string
+		pins    []string
+		wantErr error
+	}{
+		{name: "matching pin", pins: []string{fingerprint}},
+		{name: "formatted pin", pins: []string{"SHA256:" + api.FormatFingerprint(fingerprint)}},
+		{name: "one of several pins", pins: []string{"00", fingerprint}},
+		{name: "wrong pin", pins: []string{"00112233"}, wantErr: api.ErrCertificateMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := api.NewClient(server.URL,
+				api.WithCertificatePins(tt.pins...),
+				api.WithRetryPolicy(api.DefaultRetryPolicy()))
+
+			_, err := client.GetServerHealth(context.Background(), "test")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetServerHealth() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && api.IsTransientError(err) {
+				t.Errorf("IsTransientError(%v) = true, want false", err)
+			}
+		})
+	}
+}
+
+func TestCertificatePinsFailClosed(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	fingerprint := api.CertificateFingerprint(server.Certificate())
+
+	var sent int
+	custom := roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		sent++
+		return server.HTTPClient().Transport.RoundTrip(request)
+	})
+	knownHosts := api.NewKnownHosts(filepath.Join(t.TempDir(), "known_hosts"))
+
+	tests := []struct {
+		name    string
+		options []api.Option
+	}{
+		{
+			name:    "pins with custom transport",
+			options: []api.Option{api.WithTransport(custom), api.WithCertificatePins(fingerprint)},
+		},
+		{
+			name:    "pins with http client of custom transport",
+			options: []api.Option{api.WithHTTPClient(&http.Client{Transport: custom}), api.WithCertificatePins(fingerprint)},
+		},
+		{
+			name:    "trust on first use with custom transport",
+			options: []api.Option{api.WithTransport(custom), api.WithTrustOnFirstUse(knownHosts, nil)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent = 0
+			client := api.NewClient(server.URL, tt.options...)
+
+			_, err := client.GetServerHealth(context.Background(), "test")
+			if !errors.Is(err, api.ErrUnverifiableTransport) {
+				t.Fatalf("GetServerHealth() error = %v, want %v", err, api.ErrUnverifiableTransport)
+			}
+			if sent != 0 {
+				t.Errorf("custom transport sent %d requests, want 0", sent)
+			}
+		})
+	}
+}
+
+func TestCertificatePinsNeedHost(t *testing.T) {
+	knownHostsPath := filepath.Join(t.TempDir(), "known_hosts")
+	knownHosts := api.NewKnownHosts(knownHostsPath)
+
+	tests := []struct {
+		name      string
+		serverURL string
+		option    api.Option
+	}{
+		{name: "pins without scheme", serverURL: "host:7777", option: api.WithCertificatePins("00")},
+		{name: "trust on first use without scheme", serverURL: "host:7777", option: api.WithTrustOnFirstUse(knownHosts, nil)},
+		{name: "empty URL", serverURL: "", option: api.WithCertificatePins("00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := api.NewClient(tt.serverURL, tt.option, api.WithRetryPolicy(api.DefaultRetryPolicy()))
+
+			_, err := client.GetServerHealth(context.Background(), "test")
+			if !errors.Is(err, api.ErrMissingServerHost) {
+				t.Fatalf("GetServerHealth() error = %v, want %v", err, api.ErrMissingServerHost)
+			}
+			if api.IsTransientError(err) {
+				t.Errorf("IsTransientError(%v) = true, want false", err)
+			}
+			if _, err := os.Stat(knownHostsPath); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("known hosts file exists (%v), want nothing recorded", err)
+			}
+		})
+	}
+}
+
+func TestTrustOnFirstUse(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	ctx := context.Background()
+
+	knownHosts := api.NewKnownHosts(filepath.Join(t.TempDir(), "known_hosts"))
+	var confirmed []string
+	confirm := func(host string, fingerprint string) bool {
+		confirmed = append(confirmed, fingerprint)
+		return true
+	}
+
+	client := api.NewClient(server.URL, api.WithTrustOnFirstUse(knownHosts, confirm))
+	for range 2 {
+		if _, err := client.GetServerHealth(ctx, "test"); err != nil {
+			t.Fatalf("GetServerHealth() error = %v", err)
+		}
+		client.Client.CloseIdleConnections()
+	}
+	if want := api.CertificateFingerprint(server.Certificate()); len(confirmed) != 1 || confirmed[0] != want {
+		t.Fatalf("confirmed fingerprints = %v, want [%s]", confirmed, want)
+	}
+
+	host := server.URL[len("https://"):]
+	if known, found, err := knownHosts.Lookup(host); err != nil || !found || known != confirmed[0] {
+		t.Fatalf("Lookup(%s) = %s, %t, %v, want the recorded fingerprint", host, known, found, err)
+	}
+
+	// A different fingerprint recorded for the host, as if the server's certificate was regenerated.
+	changed := strings.Repeat("ab", 32)
+	if err := knownHosts.Add(host, changed); !errors.Is(err, api.ErrCertificateMismatch) {
+		t.Fatalf("Add() of a different fingerprint error = %v, want %v", err, api.ErrCertificateMismatch)
+	}
+	mismatched := api.NewKnownHosts(filepath.Join(t.TempDir(), "known_hosts"))
+	if err := mismatched.Add(host, changed); err != nil {
+		t.Fatal(err)
+	}
+	client = api.NewClient(server.URL, api.WithTrustOnFirstUse(mismatched, confirm))
+	if _, err := client.GetServerHealth(ctx, "test"); !errors.Is(err, api.ErrCertificateMismatch) {
+		t.Fatalf("GetServerHealth() with a changed certificate error = %v, want %v", err, api.ErrCertificateMismatch)
+	}
+
+	rejected := api.NewClient(server.URL, api.WithTrustOnFirstUse(
+		api.NewKnownHosts(filepath.Join(t.TempDir(), "known_hosts")),
+		func(string, string) bool { return false }))
+	if _, err := rejected.GetServerHealth(ctx, "test"); !errors.Is(err, api.ErrCertificateNotTrusted) {
+		t.Fatalf("GetServerHealth() with a rejected certificate error = %v, want %v", err, api.ErrCertificateNotTrusted)
+	}
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInsufficientPrivilege matches an *InsufficientPrivilegeError with errors.Is. It is returned by
+// clients with preflight checks enabled, before the call is sent.
+var ErrInsufficientPrivilege = errors.New("gofactory api error: insufficient privilege")
+
+// Privilege is a privilege level granted by an authentication token, such as CLIENT_PRIVILEGE.
+// Levels are ordered: NotAuthenticated < Client and InitialAdmin < Administrator and ApiToken.
+// InitialAdmin is granted on unclaimed servers, where it may claim the server and make the calls
+// Client may, but it carries no administrator rights.
+type Privilege string
+
+// privilegeRanks orders privilege levels so that a higher rank satisfies a lower one.
+var privilegeRanks = map[Privilege]int{
+	NOT_AUTHENTICATED_PRIVILEGE: 0,
+	CLIENT_PRIVILEGE:            1,
+	ADMINISTRATOR_PRIVILEGE:     2,
+	INITIAL_ADMIN_PRIVILEGE:     1,
+	API_TOKEN_PRIVILEGE:         2,
+}
+
+// Rank returns the position of the privilege level in the ordering, from 0 for NotAuthenticated
+// to 2 for Administrator and ApiToken. Unknown levels rank -1.
+func (p Privilege) Rank() int {
+	if rank, ok := privilegeRanks[p]; ok {
+		return rank
+	}
+	return -1
+}
+
+// Valid reports whether p is a known privilege level.
+func (p Privilege) Valid() bool {
+	_, ok := privilegeRanks[p]
+	return ok
+}
+
+// Satisfies reports whether a token with privilege p may call a function that requires the
+// required privilege. InitialAdmin is only granted on unclaimed servers, so a function that
+// requires it accepts nothing else.
+func (p Privilege) Satisfies(required Privilege) bool {
+	if required == INITIAL_ADMIN_PRIVILEGE {
+		return p == INITIAL_ADMIN_PRIVILEGE
+	}
+	return p.Valid() && p.Rank() >= required.Rank()
+}
+
+func (p Privilege) String() string {
+	return string(p)
+}
+
+// functionPrivileges is the minimum privilege level required by each API function.
+var functionPrivileges = map[string]Privilege{
+	HealthCheckFunction:               NOT_AUTHENTICATED_PRIVILEGE,
+	PasswordlessLoginFunction:         NOT_AUTHENTICATED_PRIVILEGE,
+	PasswordLoginFunction:             NOT_AUTHENTICATED_PRIVILEGE,
+	VerifyAuthTokenFunction:           CLIENT_PRIVILEGE,
+	QueryServerStateFunction:          CLIENT_PRIVILEGE,
+	GetServerOptionsFunction:          CLIENT_PRIVILEGE,
+	GetAdvancedGameSettingsFunction:   CLIENT_PRIVILEGE,
+	ClaimServerFunction:               INITIAL_ADMIN_PRIVILEGE,
+	ApplyAdvancedGameSettingsFunction: ADMINISTRATOR_PRIVILEGE,
+	RenameServerFunction:              ADMINISTRATOR_PRIVILEGE,
+	SetClientPasswordFunction:         ADMINISTRATOR_PRIVILEGE,
+	SetAdminPasswordFunction:          ADMINISTRATOR_PRIVILEGE,
+	SetAutoLoadSessionNameFunction:    ADMINISTRATOR_PRIVILEGE,
+	RunCommandFunction:                ADMINISTRATOR_PRIVILEGE,
+	ShutdownFunction:                  ADMINISTRATOR_PRIVILEGE,
+	ApplyServerOptionsFunction:        ADMINISTRATOR_PRIVILEGE,
+	CreateNewGameFunction:             ADMINISTRATOR_PRIVILEGE,
+	SaveGameFunction:                  ADMINISTRATOR_PRIVILEGE,
+	DeleteSaveFileFunction:            ADMINISTRATOR_PRIVILEGE,
+	DeleteSaveSessionFunction:         ADMINISTRATOR_PRIVILEGE,
+	EnumerateSessionsFunction:         ADMINISTRATOR_PRIVILEGE,
+	LoadGameFunction:                  ADMINISTRATOR_PRIVILEGE,
+	UploadSaveGameFunction:            ADMINISTRATOR_PRIVILEGE,
+	DownloadSaveGameFunction:          ADMINISTRATOR_PRIVILEGE,
+}
+
+// RequiredPrivilege returns the minimum privilege level needed to call the API function,
+// and false if the function is unknown.
+func RequiredPrivilege(functionName string) (Privilege, bool) {
+	privilege, ok := functionPrivileges[functionName]
+	return privilege, ok
+}
+
+// InsufficientPrivilegeError is returned by clients with preflight checks enabled when their
+// privilege level is too low for the called function.
+type InsufficientPrivilegeError struct {
+	// Function is the API function that was called.
+	Function string
+
+	// Required is the minimum privilege level the function needs.
+	Required Privilege
+
+	// Privilege is the privilege level the client holds.
+	Privilege Privilege
+}
+
+func (e *InsufficientPrivilegeError) Error() string {
+	return fmt.Sprintf("gofactory api error | insufficient privilege | function: %s | required: %s | have: %s",
+		e.Function, e.Required, e.Privilege)
+}
+
+// Is reports whether target is ErrInsufficientPrivilege.
+func (e *InsufficientPrivilegeError) Is(target error) bool {
+	return target == ErrInsufficientPrivilege
+}
+
+// WithPrivilegePreflight makes the client check its privilege level against the function's required
+// privilege before every call, and fail with an *InsufficientPrivilegeError instead of sending calls
+// the server would reject. Unknown functions are always sent.
+func WithPrivilegePreflight(enabled bool) Option {
+	return func(o *clientOptions) {
+		o.preflight = enabled
+	}
+}
+
+// preflightPrivilege returns an *InsufficientPrivilegeError if preflight checks are enabled and
+// the client's privilege does not satisfy the function's required privilege.
+func (c *GoFactoryClient) preflightPrivilege(functionName string) error {
+	if !c.preflight {
+		return nil
+	}
+
+	required, ok := RequiredPrivilege(functionName)
+	if !ok {
+		return nil
+	}
+
+	privilege := c.Privilege()
+	if !privilege.Satisfies(required) {
+		return &InsufficientPrivilegeError{Function: functionName, Required: required, Privilege: privilege}
+	}
+	return nil
+}
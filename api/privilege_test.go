@@ -0,0 +1,125 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+func TestPrivilegeSatisfies(t *testing.T) {
+	tests := []struct {
+		privilege api.Privilege
+		required  api.Privilege
+		want      bool
+	}{
+		{api.NOT_AUTHENTICATED_PRIVILEGE, api.NOT_AUTHENTICATED_PRIVILEGE, true},
+		{api.NOT_AUTHENTICATED_PRIVILEGE, api.CLIENT_PRIVILEGE, false},
+		{api.CLIENT_PRIVILEGE, api.CLIENT_PRIVILEGE, true},
+		{api.CLIENT_PRIVILEGE, api.ADMINISTRATOR_PRIVILEGE, false},
+		{api.CLIENT_PRIVILEGE, api.INITIAL_ADMIN_PRIVILEGE, false},
+		{api.INITIAL_ADMIN_PRIVILEGE, api.CLIENT_PRIVILEGE, true},
+		{api.INITIAL_ADMIN_PRIVILEGE, api.INITIAL_ADMIN_PRIVILEGE, true},
+		{api.INITIAL_ADMIN_PRIVILEGE, api.ADMINISTRATOR_PRIVILEGE, false},
+		{api.ADMINISTRATOR_PRIVILEGE, api.ADMINISTRATOR_PRIVILEGE, true},
+		{api.ADMINISTRATOR_PRIVILEGE, api.INITIAL_ADMIN_PRIVILEGE, false},
+		{api.API_TOKEN_PRIVILEGE, api.ADMINISTRATOR_PRIVILEGE, true},
+		{"Unknown", api.NOT_AUTHENTICATED_PRIVILEGE, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.privilege.Satisfies(tt.required); got != tt.want {
+			t.Errorf("Privilege(%q).Satisfies(%q) = %v, want %v", tt.privilege, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestPrivilegePreflight(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	unauthenticated := server.NewClient("", api.WithPrivilegePreflight(true))
+	if got := unauthenticated.Privilege(); got != api.NOT_AUTHENTICATED_PRIVILEGE {
+		t.Fatalf("empty token privilege = %s, want %s", got, api.NOT_AUTHENTICATED_PRIVILEGE)
+	}
+	initialAdmin := unauthenticated.Clone()
+	if err := initialAdmin.PasswordlessLogin(context.Background(), api.INITIAL_ADMIN_PRIVILEGE); err != nil {
+		t.Fatalf("PasswordlessLogin() error = %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		client *api.GoFactoryClient
+		call   func(ctx context.Context, client *api.GoFactoryClient) error
+
+		// function is the function the preflight check rejects, or empty if the call succeeds.
+		function string
+		required api.Privilege
+	}{
+		{
+			name:   "unauthenticated read",
+			client: unauthenticated,
+			call: func(ctx context.Context, c *api.GoFactoryClient) error {
+				_, err := c.QueryServerState(ctx)
+				return err
+			},
+			function: api.QueryServerStateFunction,
+			required: api.CLIENT_PRIVILEGE,
+		},
+		{
+			name:   "unauthenticated health check",
+			client: unauthenticated,
+			call: func(ctx context.Context, c *api.GoFactoryClient) error {
+				_, err := c.GetServerHealth(ctx, "test")
+				return err
+			},
+		},
+		{
+			name:   "initial admin read",
+			client: initialAdmin,
+			call: func(ctx context.Context, c *api.GoFactoryClient) error {
+				_, err := c.GetServerOptions(ctx)
+				return err
+			},
+		},
+		{
+			name:   "initial admin administration",
+			client: initialAdmin,
+			call: func(ctx context.Context, c *api.GoFactoryClient) error {
+				_, err := c.EnumerateSessions(ctx)
+				return err
+			},
+			function: api.EnumerateSessionsFunction,
+			required: api.ADMINISTRATOR_PRIVILEGE,
+		},
+		{
+			name:   "initial admin claim",
+			client: initialAdmin,
+			call: func(ctx context.Context, c *api.GoFactoryClient) error {
+				return c.ClaimServer(ctx, api.ClaimRequestData{ServerName: "Test", AdminPassword: "admin"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(context.Background(), tt.client)
+			if len(tt.function) == 0 {
+				if err != nil {
+					t.Fatalf("call error = %v, want nil", err)
+				}
+				return
+			}
+
+			var privilegeErr *api.InsufficientPrivilegeError
+			if !errors.As(err, &privilegeErr) {
+				t.Fatalf("call error = %v, want %v", err, api.ErrInsufficientPrivilege)
+			}
+			if privilegeErr.Function != tt.function || privilegeErr.Required != tt.required {
+				t.Errorf("error = %v, want function %s requiring %s", err, tt.function, tt.required)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// idempotentFunctions are the API functions that only read server state,
+// so they can be safely sent again after a transient failure.
+var idempotentFunctions = map[string]bool{
+	HealthCheckFunction:             true,
+	VerifyAuthTokenFunction:         true,
+	QueryServerStateFunction:        true,
+	GetServerOptionsFunction:        true,
+	GetAdvancedGameSettingsFunction: true,
+	EnumerateSessionsFunction:       true,
+	DownloadSaveGameFunction:        true,
+}
+
+// IsIdempotentFunction reports whether the API function only reads server state and is therefore
+// safe to retry. Mutating functions such as Shutdown or DeleteSaveFile are never idempotent.
+func IsIdempotentFunction(functionName string) bool {
+	return idempotentFunctions[functionName]
+}
+
+// RetryPolicy controls how the client retries API calls that fail with a transient error:
+// a transport failure such as a refused connection or timeout, or a 5xx status returned
+// while the server is restarting or loading a save.
+//
+// Only functions reported as idempotent are retried. All other calls are sent exactly once.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the delay between two attempts.
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor the delay grows by after each attempt. Values below 1 are treated as 2.
+	Multiplier float64
+
+	// Jitter is the fraction of each delay, between 0 and 1, that is randomised
+	// so that several clients do not retry in lockstep.
+	Jitter float64
+
+	// Idempotent reports whether a function may be retried. Defaults to IsIdempotentFunction
+	// when nil. Set it to allow retrying custom or modded functions.
+	Idempotent func(functionName string) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy suited to riding out a dedicated server restart,
+// retrying idempotent functions for roughly a minute.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:    8,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     15 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// allows reports whether calls to functionName may be retried under the policy.
+func (p *RetryPolicy) allows(functionName string) bool {
+	if p == nil || p.MaxAttempts < 2 {
+		return false
+	}
+	if p.Idempotent != nil {
+		return p.Idempotent(functionName)
+	}
+	return IsIdempotentFunction(functionName)
+}
+
+// backoff returns the delay to wait before the given retry, starting from 1.
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(p.InitialBackoff)
+	for range retry - 1 {
+		delay *= multiplier
+		if p.MaxBackoff > 0 && delay >= float64(p.MaxBackoff) {
+			delay = float64(p.MaxBackoff)
+			break
+		}
+	}
+
+	if p.Jitter > 0 {
+		jitter := min(p.Jitter, 1)
+		delay -= delay * jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// do calls attempt until it succeeds, returns a non-transient error, the attempts
+// run out or the context is done. The attempt number starts from 0.
+func (p *RetryPolicy) do(ctx context.Context, attempt func(n int) error) error {
+	var err error
+	for n := range p.MaxAttempts {
+		if n > 0 {
+			timer := time.NewTimer(p.backoff(n))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+
+		err = attempt(n)
+		if err == nil || !IsTransientError(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// IsTransientError reports whether err is a failure that may go away by itself, such as
+// a refused connection, a timeout or a 5xx status. Errors caused by a cancelled context, by a server
+// certificate that cannot be verified, or by a save game download that already wrote part of the save,
+// are not transient.
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrIncompleteDownload) {
+		return false
+	}
+	if errors.Is(err, ErrUnverifiableTransport) || errors.Is(err, ErrMissingServerHost) ||
+		errors.Is(err, ErrCertificateMismatch) || errors.Is(err, ErrCertificateNotTrusted) {
+		return false
+	}
+	return errors.Is(err, ErrTransport) || errors.Is(err, ErrServerError)
+}
@@ -0,0 +1,133 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	policy := func(idempotent func(string) bool) *api.RetryPolicy {
+		return &api.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Idempotent: idempotent}
+	}
+	queryState := func(client *api.GoFactoryClient) error {
+		_, err := client.QueryServerState(context.Background())
+		return err
+	}
+	rename := func(client *api.GoFactoryClient) error {
+		return client.RenameServer(context.Background(), "Renamed")
+	}
+
+	tests := []struct {
+		name         string
+		policy       *api.RetryPolicy
+		failures     int
+		call         func(client *api.GoFactoryClient) error
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "idempotent call recovers",
+			policy:       policy(nil),
+			failures:     2,
+			call:         queryState,
+			wantAttempts: 3,
+		},
+		{
+			name:         "attempts run out",
+			policy:       policy(nil),
+			failures:     5,
+			call:         queryState,
+			wantErr:      api.ErrServerError,
+			wantAttempts: 3,
+		},
+		{
+			name:         "mutating call is sent once",
+			policy:       policy(nil),
+			failures:     1,
+			call:         rename,
+			wantErr:      api.ErrServerError,
+			wantAttempts: 1,
+		},
+		{
+			name:         "custom idempotent function",
+			policy:       policy(func(function string) bool { return function == api.RenameServerFunction }),
+			failures:     1,
+			call:         rename,
+			wantAttempts: 2,
+		},
+		{
+			name:   "client error is not retried",
+			policy: policy(nil),
+			call: func(client *api.GoFactoryClient) error {
+				_, err := client.DownloadSaveGame(context.Background(), "missing")
+				return err
+			},
+			wantErr:      api.ErrFileNotFound,
+			wantAttempts: 1,
+		},
+		{
+			name:         "no policy",
+			failures:     1,
+			call:         queryState,
+			wantErr:      api.ErrServerError,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			token := server.Claim("Test", "admin")
+
+			var attempts int
+			countAttempts := func(ctx context.Context, call *api.Call, next api.Invoker) error {
+				err := next(ctx, call)
+				attempts = call.Attempts
+				return err
+			}
+			client := server.NewClient(token, api.WithInterceptors(countAttempts), api.WithRetryPolicy(tt.policy))
+			server.FailNext(tt.failures, http.StatusServiceUnavailable)
+
+			if err := tt.call(client); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("call error = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestIsTransientError(t *testing.T) {
+	transport := func(err error) error {
+		return &api.TransportError{Function: api.QueryServerStateFunction, Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"refused connection", transport(errors.New("connection refused")), true},
+		{"server error", &api.APIError{HTTPStatusCode: http.StatusServiceUnavailable}, true},
+		{"client error", &api.APIError{StatusCode: api.ErrorCodeFileNotFound, HTTPStatusCode: http.StatusNotFound}, false},
+		{"canceled", transport(context.Canceled), false},
+		{"certificate mismatch", transport(api.ErrCertificateMismatch), false},
+		{"incomplete download", transport(fmt.Errorf("%w: received 1 of 2 bytes", api.ErrIncompleteDownload)), false},
+	}
+
+	for _, tt := range tests {
+		if got := api.IsTransientError(tt.err); got != tt.want {
+			t.Errorf("IsTransientError(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,9 +3,16 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"mime"
 	"mime/multipart"
+	"net/http"
+	"net/textproto"
 )
 
 // CreateNewGameRequest represents the request payload for creating a new game session.
@@ -49,32 +56,9 @@ type CreateNewGameRequestData struct {
 // It takes a CreateNewGameRequestData struct parameter to specify the
 // session name, map, starting location, and advanced game settings.
 func (c *GoFactoryClient) CreateNewGame(ctx context.Context, newGameData CreateNewGameRequestData) error {
-	functionBody, err := json.Marshal(CreateNewGameRequest{
-		Function: CreateNewGameFunction,
-		Data: CreateNewGameData{
-			GameData: newGameData,
-		},
-	})
-	if err != nil {
-		return err
-	}
-
-	request, err := c.CreatePostRequest(CreateNewGameFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	var apiError APIError
-	err = c.SendPostRequest(ctx, request, &apiError)
-	if err != nil {
-		return err
-	}
-
-	if apiError != (APIError{}) {
-		return &apiError
-	}
-
-	return nil
+	_, err := CallFunction[CreateNewGameData, Empty](ctx, c, CreateNewGameFunction,
+		CreateNewGameData{GameData: newGameData})
+	return err
 }
 
 // SaveGameRequest represents a request to save the current loaded save game.
@@ -94,31 +78,8 @@ type SaveGameData struct {
 
 // SaveGame saves the current game session and applies `saveName` as the name.
 func (c *GoFactoryClient) SaveGame(ctx context.Context, saveName string) error {
-	functionBody, err := json.Marshal(SaveGameRequest{
-		Function: SaveGameFunction,
-		Data: SaveGameData{
-			SaveName: saveName,
-		},
-	})
-	if err != nil {
-		return err
-	}
-	request, err := c.CreatePostRequest(SaveGameFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	var apiError APIError
-	err = c.SendPostRequest(ctx, request, &apiError)
-	if err != nil {
-		return err
-	}
-
-	if apiError != (APIError{}) {
-		return &apiError
-	}
-
-	return nil
+	_, err := CallFunction[SaveGameData, Empty](ctx, c, SaveGameFunction, SaveGameData{SaveName: saveName})
+	return err
 }
 
 // DeleteSaveRequest represents a request to delete a specific save file
@@ -139,32 +100,8 @@ type DeleteSaveData struct {
 
 // DeleteSave deletes the save file matching saveName
 func (c *GoFactoryClient) DeleteSave(ctx context.Context, saveName string) error {
-	functionBody, err := json.Marshal(DeleteSaveRequest{
-		Function: DeleteSaveFileFunction,
-		Data: DeleteSaveData{
-			SaveName: saveName,
-		},
-	})
-	if err != nil {
-		return err
-	}
-
-	req, err := c.CreatePostRequest(DeleteSaveFileFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	var apiError APIError
-	err = c.SendPostRequest(ctx, req, &apiError)
-	if err != nil {
-		return err
-	}
-
-	if apiError != (APIError{}) {
-		return &apiError
-	}
-
-	return nil
+	_, err := CallFunction[DeleteSaveData, Empty](ctx, c, DeleteSaveFileFunction, DeleteSaveData{SaveName: saveName})
+	return err
 }
 
 // DeleteSaveSessionRequest represents a request to delete a specific save session
@@ -185,32 +122,9 @@ type DeleteSaveSessionData struct {
 
 // DeleteSaveSession deletes all saves files that belong to a specific session.
 func (c *GoFactoryClient) DeleteSaveSession(ctx context.Context, sessionName string) error {
-	functionBody, err := json.Marshal(DeleteSaveSessionRequest{
-		Function: DeleteSaveSessionFunction,
-		Data: DeleteSaveSessionData{
-			SessionName: sessionName,
-		},
-	})
-	if err != nil {
-		return err
-	}
-
-	req, err := c.CreatePostRequest(DeleteSaveSessionFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	var apiError APIError
-	err = c.SendPostRequest(ctx, req, &apiError)
-	if err != nil {
-		return err
-	}
-
-	if apiError != (APIError{}) {
-		return &apiError
-	}
-
-	return nil
+	_, err := CallFunction[DeleteSaveSessionData, Empty](ctx, c, DeleteSaveSessionFunction,
+		DeleteSaveSessionData{SessionName: sessionName})
+	return err
 }
 
 // EnumerateSessionsResponse represents the response from the Satisfactory server
@@ -280,12 +194,7 @@ type EnumerateSessionsSaveHeader struct {
 
 // EnumerateSessions will return a complete slice of all sessions enumerated in the Satisfactory dedicated server.
 func (c *GoFactoryClient) EnumerateSessions(ctx context.Context) (*EnumerateSessionsResponseData, error) {
-	response, err := CreateAndSendPostRequest[EnumerateSessionsResponse](ctx, c,
-		EnumerateSessionsFunction, CreateGenericFunctionBody(EnumerateSessionsFunction))
-	if err != nil {
-		return nil, err
-	}
-	return &response.Data, nil
+	return CallFunction[Empty, EnumerateSessionsResponseData](ctx, c, EnumerateSessionsFunction, Empty{})
 }
 
 // LoadGameRequest represents a request to load a saved game session
@@ -306,53 +215,21 @@ type LoadGameRequestData struct {
 
 	// EnableAdvanceGameSettings specifies whether advanced game settings
 	// should be applied when loading the save.
-	EnableAdvanceGameSettings bool `json:"enableAdvanceGameSettings"`
+	EnableAdvanceGameSettings bool `json:"enableAdvancedGameSettings"`
 }
 
 // LoadGame will load the specified game save that matches `saveName` and an boolean to specify
 // if Advanced Game Settings should be enabled when this save is loaded.
 func (c *GoFactoryClient) LoadGame(ctx context.Context, saveName string, enableAdvancedSettings bool) error {
-	functionBody, err := json.Marshal(LoadGameRequest{
-		Function: LoadGameFunction,
-		Data: LoadGameRequestData{
-			SaveName:                  saveName,
-			EnableAdvanceGameSettings: enableAdvancedSettings,
-		},
+	_, err := CallFunction[LoadGameRequestData, Empty](ctx, c, LoadGameFunction, LoadGameRequestData{
+		SaveName:                  saveName,
+		EnableAdvanceGameSettings: enableAdvancedSettings,
 	})
-	if err != nil {
-		return err
-	}
-
-	req, err := c.CreatePostRequest(LoadGameFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	var apiError APIError
-	err = c.SendPostRequest(ctx, req, &apiError)
-	if err != nil {
-		return err
-	}
-
-	if apiError != (APIError{}) {
-		return &apiError
-	}
-
-	return nil
+	return err
 }
 
-// UploadSaveGameRequest represents the request to upload a save game file
-// to the Satisfactory server, including the data and file buffer.
-type UploadSaveGameRequest struct {
-	// Data contains the function name and save game details.
-	Data UploadSaveGameData `json:"data"`
-
-	// SaveFile is the binary data of the save game file to upload.
-	SaveFile bytes.Buffer `json:"saveGameFile"`
-}
-
-// UploadSaveGameData contains the API function name and the details
-// about how the save game should be handled after upload.
+// UploadSaveGameData is the function request sent in the "data" part of the multipart
+// UploadSaveGame request, next to the "saveGameFile" part holding the save file.
 type UploadSaveGameData struct {
 	// Function specifies the API function to call for uploading the save game.
 	Function string `json:"function"`
@@ -372,58 +249,90 @@ type UploadSaveGameDataRequest struct {
 
 	// EnableAdvanceGameSettings specifies whether advanced game settings
 	// should be applied when loading the uploaded save.
-	EnableAdvanceGameSettings bool `json:"enableAdvanceGameSettings"`
+	EnableAdvanceGameSettings bool `json:"enableAdvancedGameSettings"`
 }
 
 // UploadSaveGame uploads a save game file to the Satisfactory server.
 // It streams the file from a Reader and informs the server what to do with it through the UploadSaveGameDataRequest
-// parameter.
+// parameter. See UploadSaveGameWithProgress.
 func (c *GoFactoryClient) UploadSaveGame(ctx context.Context, fileStream io.Reader, filename string, saveSettings UploadSaveGameDataRequest) error {
-	var bodyBuffer bytes.Buffer
-	multipartWriter := multipart.NewWriter(&bodyBuffer)
-	defer multipartWriter.Close()
+	return c.UploadSaveGameWithProgress(ctx, fileStream, filename, saveSettings, nil)
+}
 
-	fileWriter, err := multipartWriter.CreateFormFile("save", filename)
+// UploadSaveGameWithProgress uploads a save game file to the Satisfactory server, calling progress as
+// the file is sent if it is not nil. The file is streamed from fileStream into the multipart request
+// without being held in memory. When fileStream is an in-memory reader or a regular *os.File, the
+// request is sent with a Content-Length and progress receives the size of the file, otherwise the
+// request is chunked. As the body cannot be read twice, uploads are never retried.
+func (c *GoFactoryClient) UploadSaveGameWithProgress(ctx context.Context, fileStream io.Reader, filename string, saveSettings UploadSaveGameDataRequest, progress ProgressFunc) error {
+	if len(filename) == 0 {
+		filename = saveSettings.SaveName + ".sav"
+	}
+
+	data, err := json.Marshal(UploadSaveGameData{
+		Function: UploadSaveGameFunction,
+		SaveData: saveSettings,
+	})
 	if err != nil {
 		return err
 	}
 
-	_, err = io.Copy(fileWriter, fileStream)
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeReader.Close()
+	multipartWriter := multipart.NewWriter(pipeWriter)
+
+	req, err := c.newPostRequest(UploadSaveGameFunction, pipeReader, multipartWriter.FormDataContentType())
 	if err != nil {
 		return err
 	}
 
-	headers := map[string]string{
-		"Content-Type": multipartWriter.FormDataContentType(),
+	size := readerSize(fileStream)
+	req.ContentLength = -1
+	if size >= 0 {
+		var overhead byteCounter
+		emptyWriter := multipart.NewWriter(&overhead)
+		if err := emptyWriter.SetBoundary(multipartWriter.Boundary()); err != nil {
+			return err
+		}
+		if err := writeUploadParts(emptyWriter, data, filename, nil); err != nil {
+			return err
+		}
+		req.ContentLength = int64(overhead) + size
 	}
 
-	functionBody, err := json.Marshal(UploadSaveGameRequest{
-		Data: UploadSaveGameData{
-			Function: UploadSaveGameFunction,
-			SaveData: saveSettings,
-		},
-		SaveFile: bodyBuffer,
-	})
+	go func() {
+		err := writeUploadParts(multipartWriter, data, filename, withProgress(fileStream, size, progress))
+		pipeWriter.CloseWithError(err)
+	}()
+
+	return c.SendPostRequest(ctx, req, nil)
+}
+
+// writeUploadParts writes the "data" and "saveGameFile" parts of an UploadSaveGame request
+// and closes the multipart writer. A nil file writes an empty file part.
+func writeUploadParts(multipartWriter *multipart.Writer, data []byte, filename string, file io.Reader) error {
+	dataHeader := make(textproto.MIMEHeader)
+	dataHeader.Set("Content-Disposition", `form-data; name="data"`)
+	dataHeader.Set("Content-Type", "application/json")
+	dataWriter, err := multipartWriter.CreatePart(dataHeader)
 	if err != nil {
 		return err
 	}
-
-	req, err := c.CreatePostRequestWithHeaders(headers, UploadSaveGameFunction, functionBody)
-	if err != nil {
+	if _, err := dataWriter.Write(data); err != nil {
 		return err
 	}
 
-	var apiError APIError
-	err = c.SendPostRequest(ctx, req, &apiError)
+	fileWriter, err := multipartWriter.CreateFormFile("saveGameFile", filename)
 	if err != nil {
 		return err
 	}
-
-	if apiError != (APIError{}) {
-		return &apiError
+	if file != nil {
+		if _, err := io.Copy(fileWriter, file); err != nil {
+			return fmt.Errorf("cannot read save game file: %w", err)
+		}
 	}
 
-	return nil
+	return multipartWriter.Close()
 }
 
 // DownloadSaveGameRequest represents a request to download a save game file
@@ -442,9 +351,46 @@ type DownloadSaveGameRequestData struct {
 	SaveName string `json:"saveName"`
 }
 
-// DownloadSaveGame downloads a save game file from the Satisfactory dedicated server.
-// It returns a binary []byte stream of the file from the returned request body.
+// ErrIncompleteDownload is returned in a *TransportError when a save game download ends before
+// the size announced by the server's Content-Length, or the connection fails while it is received.
+// Such downloads are not retried, as part of the save was already written.
+var ErrIncompleteDownload = errors.New("gofactory transport error: incomplete save game download")
+
+// DownloadSaveGameResult describes a save game file written by DownloadSaveGameTo.
+type DownloadSaveGameResult struct {
+	// Filename is the file name sent by the server, if any.
+	Filename string
+
+	// Bytes is the number of bytes written.
+	Bytes int64
+
+	// SHA256 is the hex-encoded SHA-256 checksum of the bytes written.
+	SHA256 string
+}
+
+// DownloadSaveGame downloads a save game file from the Satisfactory dedicated server and returns its
+// contents. Use DownloadSaveGameTo to write large saves to a file instead of holding them in memory.
 func (c *GoFactoryClient) DownloadSaveGame(ctx context.Context, saveName string) ([]byte, error) {
+	var fileStream bytes.Buffer
+	_, err := c.DownloadSaveGameTo(ctx, saveName, &fileStream)
+	if err != nil {
+		return nil, err
+	}
+	return fileStream.Bytes(), nil
+}
+
+// DownloadSaveGameTo downloads a save game file from the Satisfactory dedicated server and streams it
+// to w. See DownloadSaveGameWithProgress.
+func (c *GoFactoryClient) DownloadSaveGameTo(ctx context.Context, saveName string, w io.Writer) (*DownloadSaveGameResult, error) {
+	return c.DownloadSaveGameWithProgress(ctx, saveName, w, nil)
+}
+
+// DownloadSaveGameWithProgress downloads a save game file from the Satisfactory dedicated server and
+// streams it to w, calling progress as it is received if it is not nil. Error responses are returned
+// as an *APIError without writing anything to w, and are retried and re-authenticated like other calls.
+// If the download fails once the save is being received, the error matches ErrIncompleteDownload,
+// w holds a partial file, and the download is not retried.
+func (c *GoFactoryClient) DownloadSaveGameWithProgress(ctx context.Context, saveName string, w io.Writer, progress ProgressFunc) (*DownloadSaveGameResult, error) {
 	functionBody, err := json.Marshal(DownloadSaveGameRequest{
 		Function: DownloadSaveGameFunction,
 		Data: DownloadSaveGameRequestData{
@@ -460,16 +406,53 @@ func (c *GoFactoryClient) DownloadSaveGame(ctx context.Context, saveName string)
 		return nil, err
 	}
 
-	resp, err := c.Client.Do(req.WithContext(ctx))
+	var result DownloadSaveGameResult
+	err = c.send(ctx, req, func(resp *http.Response, call *Call) error {
+		if resp.StatusCode != http.StatusOK {
+			return decodeAPIError(call.Function, resp)
+		}
+
+		if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+			result.Filename = params["filename"]
+		}
+
+		hash := sha256.New()
+		destination := &saveWriter{writer: io.MultiWriter(w, hash)}
+		body := withProgress(&countingReader{reader: resp.Body, count: &call.ResponseBytes}, resp.ContentLength, progress)
+
+		var err error
+		result.Bytes, err = io.Copy(destination, body)
+		result.SHA256 = hex.EncodeToString(hash.Sum(nil))
+		if destination.err != nil {
+			return fmt.Errorf("cannot write save game %s: %w", saveName, destination.err)
+		}
+		if resp.ContentLength >= 0 && result.Bytes != resp.ContentLength {
+			return &TransportError{Function: DownloadSaveGameFunction, Err: fmt.Errorf("%w: received %d of %d bytes",
+				ErrIncompleteDownload, result.Bytes, resp.ContentLength)}
+		}
+		if err != nil {
+			return &TransportError{Function: DownloadSaveGameFunction, Err: fmt.Errorf("%w: received %d bytes: %w",
+				ErrIncompleteDownload, result.Bytes, err)}
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	fileStream, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
+	return &result, nil
+}
 
-	return fileStream, nil
+// saveWriter records the first error returned by writer, to tell it apart from errors reading the response.
+type saveWriter struct {
+	writer io.Writer
+	err    error
+}
+
+func (w *saveWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
 }
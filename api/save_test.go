@@ -0,0 +1,315 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+// truncatingMiddleware cuts the body of every response after n bytes, keeping its Content-Length.
+func truncatingMiddleware(n int64) func(http.RoundTripper) http.RoundTripper {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(request)
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{io.LimitReader(resp.Body, n), resp.Body}
+			return resp, nil
+		})
+	}
+}
+
+// failingReader returns err once the reader it wraps is exhausted.
+type failingReader struct {
+	reader io.Reader
+	err    error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err == io.EOF {
+		return n, r.err
+	}
+	return n, err
+}
+
+func TestUploadSaveGame(t *testing.T) {
+	save := bytes.Repeat([]byte("factory"), 1024)
+	path := filepath.Join(t.TempDir(), "Factory.sav")
+	if err := os.WriteFile(path, save, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+
+		// file returns the reader to upload, which is closed by the test if it is an io.Closer.
+		file     func(t *testing.T) io.Reader
+		saveName string
+
+		wantSave    []byte
+		wantChunked bool
+		wantTotal   int64
+		wantErr     error
+		wantErrText string
+	}{
+		{
+			name:      "in-memory reader",
+			file:      func(*testing.T) io.Reader { return bytes.NewReader(save) },
+			saveName:  "Upload",
+			wantSave:  save,
+			wantTotal: int64(len(save)),
+		},
+		{
+			name: "file read from an offset",
+			file: func(t *testing.T) io.Reader {
+				file, err := os.Open(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := file.Seek(7, io.SeekStart); err != nil {
+					t.Fatal(err)
+				}
+				return file
+			},
+			saveName:  "Upload",
+			wantSave:  save[7:],
+			wantTotal: int64(len(save) - 7),
+		},
+		{
+			name:        "reader of unknown size",
+			file:        func(*testing.T) io.Reader { return io.MultiReader(bytes.NewReader(save)) },
+			saveName:    "Upload",
+			wantSave:    save,
+			wantChunked: true,
+			wantTotal:   -1,
+		},
+		{
+			name:      "missing save name",
+			file:      func(*testing.T) io.Reader { return bytes.NewReader(save) },
+			wantTotal: int64(len(save)),
+			wantErr:   api.ErrMissingParams,
+		},
+		{
+			name: "failing reader",
+			file: func(*testing.T) io.Reader {
+				return &failingReader{reader: io.MultiReader(bytes.NewReader(save)), err: errors.New("disk removed")}
+			},
+			saveName:    "Upload",
+			wantChunked: true,
+			wantTotal:   -1,
+			wantErr:     api.ErrTransport,
+			wantErrText: "disk removed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			token := server.Claim("Test", "admin")
+
+			var contentLength atomic.Int64
+			recordLength := func(next http.RoundTripper) http.RoundTripper {
+				return roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+					contentLength.Store(request.ContentLength)
+					return next.RoundTrip(request)
+				})
+			}
+			client := server.NewClient(token, api.WithMiddleware(recordLength))
+
+			file := tt.file(t)
+			if closer, ok := file.(io.Closer); ok {
+				defer closer.Close()
+			}
+			var sent, total int64
+			progress := func(transferred int64, size int64) {
+				sent, total = transferred, size
+			}
+
+			settings := api.UploadSaveGameDataRequest{SaveName: tt.saveName, LoadImmediately: true}
+			err := client.UploadSaveGameWithProgress(context.Background(), file, "Factory.sav", settings, progress)
+			if !errors.Is(err, tt.wantErr) || (err != nil && !strings.Contains(err.Error(), tt.wantErrText)) {
+				t.Fatalf("UploadSaveGameWithProgress() error = %v, want %v containing %q", err, tt.wantErr, tt.wantErrText)
+			}
+			if chunked := contentLength.Load() < 0; chunked != tt.wantChunked {
+				t.Errorf("request Content-Length = %d, want chunked %v", contentLength.Load(), tt.wantChunked)
+			}
+			if total != tt.wantTotal {
+				t.Errorf("progress total = %d, want %d", total, tt.wantTotal)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if got, ok := server.Save(tt.saveName); !ok || !bytes.Equal(got, tt.wantSave) {
+				t.Errorf("server holds %d bytes for save %s, want %d", len(got), tt.saveName, len(tt.wantSave))
+			}
+			if sent != int64(len(tt.wantSave)) {
+				t.Errorf("progress sent = %d, want %d", sent, len(tt.wantSave))
+			}
+			if got := server.ActiveSessionName(); got != tt.saveName {
+				t.Errorf("active session = %q, want the uploaded save loaded", got)
+			}
+		})
+	}
+}
+
+func TestDownloadSaveGameSendPath(t *testing.T) {
+	save := bytes.Repeat([]byte("factory"), 512)
+
+	tests := []struct {
+		name string
+
+		// setup prepares the server for the download and returns extra client options.
+		setup   func(server *apitest.Server, token string) []api.Option
+		wantErr error
+
+		// wantAttempts counts the download requests sent, including the one resent after re-authenticating.
+		wantAttempts int
+	}{
+		{
+			name:         "succeeds",
+			setup:        func(*apitest.Server, string) []api.Option { return nil },
+			wantAttempts: 1,
+		},
+		{
+			name: "retried after server errors",
+			setup: func(server *apitest.Server, _ string) []api.Option {
+				server.FailNext(2, http.StatusServiceUnavailable)
+				return nil
+			},
+			wantAttempts: 3,
+		},
+		{
+			name: "re-authenticated after a revoked token",
+			setup: func(server *apitest.Server, token string) []api.Option {
+				server.RevokeToken(token)
+				return []api.Option{api.WithCredentialProvider(api.PasswordCredentials(api.ADMINISTRATOR_PRIVILEGE, "admin"))}
+			},
+			wantAttempts: 2,
+		},
+		{
+			name: "truncated body is not retried",
+			setup: func(*apitest.Server, string) []api.Option {
+				return []api.Option{api.WithMiddleware(truncatingMiddleware(100))}
+			},
+			wantErr:      api.ErrIncompleteDownload,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			token := server.Claim("Test", "admin")
+			server.AddSave("Session", "Save", save)
+
+			var attempts atomic.Int32
+			countAttempts := func(ctx context.Context, call *api.Call, next api.Invoker) error {
+				err := next(ctx, call)
+				if call.Function == api.DownloadSaveGameFunction {
+					attempts.Add(int32(call.Attempts))
+				}
+				return err
+			}
+			opts := append(tt.setup(server, token),
+				api.WithInterceptors(countAttempts),
+				api.WithRetryPolicy(&api.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+			client := server.NewClient(token, opts...)
+
+			var file bytes.Buffer
+			result, err := client.DownloadSaveGameTo(context.Background(), "Save", &file)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DownloadSaveGameTo() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := int(attempts.Load()); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			if !bytes.Equal(file.Bytes(), save) || result.Bytes != int64(len(save)) || result.Filename != "Save.sav" {
+				t.Errorf("downloaded %d bytes as %q, want the %d bytes of Save.sav", result.Bytes, result.Filename, len(save))
+			}
+			if sum := sha256.Sum256(save); result.SHA256 != hex.EncodeToString(sum[:]) {
+				t.Errorf("download checksum = %s, want %x", result.SHA256, sum)
+			}
+		})
+	}
+}
+
+// failingWriter fails every write, counting the bytes it was given.
+type failingWriter struct {
+	received int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.received += len(p)
+	return 0, errors.New("disk full")
+}
+
+func TestDownloadSaveGameChecks(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+	token := server.Claim("Test", "admin")
+	server.AddSave("Session", "Save", bytes.Repeat([]byte("factory"), 512))
+	client := server.NewClient(token)
+
+	tests := []struct {
+		name        string
+		saveName    string
+		wantErr     error
+		wantErrText string
+	}{
+		{
+			name:        "missing save",
+			saveName:    "Missing",
+			wantErr:     api.ErrFileNotFound,
+			wantErrText: "Missing",
+		},
+		{
+			name:        "failing writer",
+			saveName:    "Save",
+			wantErrText: "cannot write save game Save: disk full",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var progressCalls int
+			writer := &failingWriter{}
+			_, err := client.DownloadSaveGameWithProgress(context.Background(), tt.saveName, writer, func(int64, int64) {
+				progressCalls++
+			})
+			if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) || !strings.Contains(err.Error(), tt.wantErrText) {
+				t.Fatalf("DownloadSaveGameWithProgress() error = %v, want %v containing %q", err, tt.wantErr, tt.wantErrText)
+			}
+			if errors.Is(err, api.ErrIncompleteDownload) || api.IsTransientError(err) {
+				t.Errorf("DownloadSaveGameWithProgress() error = %v, want a permanent error", err)
+			}
+			if tt.wantErr != nil && (writer.received != 0 || progressCalls != 0) {
+				t.Errorf("error response wrote %d bytes and reported progress %d times, want none", writer.received, progressCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Wire keys of the server options, as used by ServerOptions.
+const (
+	AutoPauseOption             = "FG.DSAutoPause"
+	AutoSaveOnDisconnectOption  = "FG.DSAutoSaveOnDisconnect"
+	DisableSeasonalEventsOption = "FG.DisableSeasonalEvents"
+	AutosaveIntervalOption      = "FG.AutosaveInterval"
+	ServerRestartTimeSlotOption = "FG.ServerRestartTimeSlot"
+	SendGameplayDataOption      = "FG.SendGameplayData"
+	NetworkQualityOption        = "FG.NetworkQuality"
+)
+
+// ErrInvalidServerOption matches an *InvalidServerOptionError with errors.Is.
+var ErrInvalidServerOption = errors.New("gofactory api error: invalid server option")
+
+// InvalidServerOptionError is returned when a server option holds a value the server would ignore,
+// such as "true" instead of "True" for a boolean option.
+type InvalidServerOptionError struct {
+	// Key is the wire key of the option, such as FG.DSAutoPause.
+	Key string
+
+	// Value is the rejected value.
+	Value string
+
+	// Reason describes the values the option accepts.
+	Reason string
+}
+
+func (e *InvalidServerOptionError) Error() string {
+	return fmt.Sprintf("gofactory api error | invalid server option | %s: %q | %s", e.Key, e.Value, e.Reason)
+}
+
+// Is reports whether target is ErrInvalidServerOption.
+func (e *InvalidServerOptionError) Is(target error) bool {
+	return target == ErrInvalidServerOption
+}
+
+// NetworkQuality is the network quality mode of the server, sent as 0 to 3 in FG.NetworkQuality.
+type NetworkQuality int
+
+// Network quality modes, from the lowest bandwidth to the highest.
+const (
+	NetworkQualityLow NetworkQuality = iota
+	NetworkQualityMedium
+	NetworkQualityHigh
+	NetworkQualityUltra
+)
+
+var networkQualityNames = [...]string{"Low", "Medium", "High", "Ultra"}
+
+// Valid reports whether q is a known network quality mode.
+func (q NetworkQuality) Valid() bool {
+	return q >= NetworkQualityLow && q <= NetworkQualityUltra
+}
+
+func (q NetworkQuality) String() string {
+	if !q.Valid() {
+		return "NetworkQuality(" + strconv.Itoa(int(q)) + ")"
+	}
+	return networkQualityNames[q]
+}
+
+// ParseNetworkQuality parses a network quality mode from its name, ignoring case, or its number from 0 to 3.
+func ParseNetworkQuality(s string) (NetworkQuality, error) {
+	for i, name := range networkQualityNames {
+		if strings.EqualFold(s, name) {
+			return NetworkQuality(i), nil
+		}
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || !NetworkQuality(n).Valid() {
+		return 0, fmt.Errorf("invalid network quality %q: must be one of %s, or 0 to 3",
+			s, strings.Join(networkQualityNames[:], ", "))
+	}
+	return NetworkQuality(n), nil
+}
+
+// maxServerRestartTimeSlot is the largest restart time slot, in minutes after midnight.
+const maxServerRestartTimeSlot = 24 * time.Hour
+
+// TypedServerOptions is a typed view of ServerOptions. Nil fields are options that are not set,
+// and are left unchanged by ApplyTypedServerOptions. It is marshalled to and from JSON with the
+// same FG.* keys and string values as ServerOptions.
+type TypedServerOptions struct {
+	// AutoPause controls whether the server will pause automatically when no-one is connected.
+	AutoPause *bool
+
+	// AutoSaveOnDisconnect determines whether to automatically save the game when a player disconnects.
+	AutoSaveOnDisconnect *bool
+
+	// DisableSeasonalEvents disables any seasonal events in the game.
+	DisableSeasonalEvents *bool
+
+	// AutosaveInterval is the interval at which the game autosaves, in whole seconds.
+	AutosaveInterval *time.Duration
+
+	// ServerRestartTimeSlot is the time of day of the scheduled server restart, as the time
+	// after midnight in whole minutes, up to 24 hours.
+	ServerRestartTimeSlot *time.Duration
+
+	// SendGameplayData determines whether to send gameplay data to Ficsit.
+	SendGameplayData *bool
+
+	// NetworkQuality sets the network quality mode for the server.
+	NetworkQuality *NetworkQuality
+
+	// Extra holds the options that have no field, as in ServerOptions. They are not validated.
+	Extra map[string]string
+}
+
+// Ptr returns a pointer to v, to set the fields of TypedServerOptions.
+func Ptr[T any](v T) *T {
+	return &v
+}
+
+// Typed parses the options into a TypedServerOptions. It returns an error matching ErrInvalidServerOption
+// for every option the server would not accept.
+func (o ServerOptions) Typed() (TypedServerOptions, error) {
+	typed := TypedServerOptions{Extra: maps.Clone(o.Extra)}
+	var errs []error
+
+	parse := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	parse(parseBoolOption(AutoPauseOption, o.AutoPause, &typed.AutoPause))
+	parse(parseBoolOption(AutoSaveOnDisconnectOption, o.AutoSaveOnDisconnect, &typed.AutoSaveOnDisconnect))
+	parse(parseBoolOption(DisableSeasonalEventsOption, o.DisableSeasonalEvents, &typed.DisableSeasonalEvents))
+	parse(parseDurationOption(AutosaveIntervalOption, o.AutosaveInterval, time.Second, 0, &typed.AutosaveInterval))
+	parse(parseDurationOption(ServerRestartTimeSlotOption, o.ServerRestartTimeSlot, time.Minute, maxServerRestartTimeSlot,
+		&typed.ServerRestartTimeSlot))
+	parse(parseBoolOption(SendGameplayDataOption, o.SendGameplayData, &typed.SendGameplayData))
+
+	if len(o.NetworkQuality) != 0 {
+		quality, err := strconv.Atoi(o.NetworkQuality)
+		if err != nil || !NetworkQuality(quality).Valid() {
+			parse(&InvalidServerOptionError{Key: NetworkQualityOption, Value: o.NetworkQuality, Reason: "must be 0 to 3"})
+		} else {
+			typed.NetworkQuality = Ptr(NetworkQuality(quality))
+		}
+	}
+
+	return typed, errors.Join(errs...)
+}
+
+// Validate returns an error matching ErrInvalidServerOption for every option the server would not accept.
+func (o ServerOptions) Validate() error {
+	_, err := o.Typed()
+	return err
+}
+
+// ServerOptions converts the typed options to their wire form. It returns an error matching
+// ErrInvalidServerOption for every option out of range.
+func (t TypedServerOptions) ServerOptions() (ServerOptions, error) {
+	options := ServerOptions{Extra: maps.Clone(t.Extra)}
+	var errs []error
+
+	format := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	options.AutoPause = formatBoolOption(t.AutoPause)
+	options.AutoSaveOnDisconnect = formatBoolOption(t.AutoSaveOnDisconnect)
+	options.DisableSeasonalEvents = formatBoolOption(t.DisableSeasonalEvents)
+	options.SendGameplayData = formatBoolOption(t.SendGameplayData)
+	format(formatDurationOption(AutosaveIntervalOption, t.AutosaveInterval, time.Second, 0, &options.AutosaveInterval))
+	format(formatDurationOption(ServerRestartTimeSlotOption, t.ServerRestartTimeSlot, time.Minute, maxServerRestartTimeSlot,
+		&options.ServerRestartTimeSlot))
+
+	if t.NetworkQuality != nil {
+		if !t.NetworkQuality.Valid() {
+			format(&InvalidServerOptionError{Key: NetworkQualityOption, Value: strconv.Itoa(int(*t.NetworkQuality)),
+				Reason: "must be 0 to 3"})
+		} else {
+			options.NetworkQuality = strconv.Itoa(int(*t.NetworkQuality))
+		}
+	}
+
+	return options, errors.Join(errs...)
+}
+
+// Validate returns an error matching ErrInvalidServerOption for every option out of range.
+func (t TypedServerOptions) Validate() error {
+	_, err := t.ServerOptions()
+	return err
+}
+
+// MarshalJSON encodes the options with their FG.* wire keys and string values.
+func (t TypedServerOptions) MarshalJSON() ([]byte, error) {
+	options, err := t.ServerOptions()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(options)
+}
+
+// UnmarshalJSON decodes options with their FG.* wire keys and string values.
+func (t *TypedServerOptions) UnmarshalJSON(data []byte) error {
+	var options ServerOptions
+	if err := json.Unmarshal(data, &options); err != nil {
+		return err
+	}
+
+	typed, err := options.Typed()
+	if err != nil {
+		return err
+	}
+	*t = typed
+	return nil
+}
+
+// ApplyTypedServerOptions validates the typed options and applies them to the Satisfactory dedicated server.
+func (c *GoFactoryClient) ApplyTypedServerOptions(ctx context.Context, options TypedServerOptions) error {
+	wire, err := options.ServerOptions()
+	if err != nil {
+		return err
+	}
+	return c.ApplyServerOptions(ctx, wire)
+}
+
+// parseBoolOption parses a "True" or "False" option value into target, leaving it nil if value is empty.
+func parseBoolOption(key string, value string, target **bool) error {
+	switch value {
+	case "":
+		return nil
+	case "True":
+		*target = Ptr(true)
+	case "False":
+		*target = Ptr(false)
+	default:
+		return &InvalidServerOptionError{Key: key, Value: value, Reason: `must be "True" or "False"`}
+	}
+	return nil
+}
+
+// formatBoolOption formats a boolean option as "True" or "False", or an empty string if it is nil.
+func formatBoolOption(value *bool) string {
+	switch {
+	case value == nil:
+		return ""
+	case *value:
+		return "True"
+	default:
+		return "False"
+	}
+}
+
+// parseDurationOption parses an option holding a whole number of units into target, leaving it nil if
+// value is empty. Values must not be negative nor, if limit is not zero, greater than limit.
+func parseDurationOption(key string, value string, unit time.Duration, limit time.Duration, target **time.Duration) error {
+	if len(value) == 0 {
+		return nil
+	}
+
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil || n != math.Trunc(n) || n < 0 || (limit != 0 && time.Duration(n)*unit > limit) {
+		return &InvalidServerOptionError{Key: key, Value: value, Reason: durationOptionReason(unit, limit)}
+	}
+	*target = Ptr(time.Duration(n) * unit)
+	return nil
+}
+
+// formatDurationOption formats a duration option as a whole number of units into target, leaving it
+// empty if value is nil.
+func formatDurationOption(key string, value *time.Duration, unit time.Duration, limit time.Duration, target *string) error {
+	if value == nil {
+		return nil
+	}
+
+	d := *value
+	if d < 0 || d%unit != 0 || (limit != 0 && d > limit) {
+		return &InvalidServerOptionError{Key: key, Value: d.String(), Reason: durationOptionReason(unit, limit)}
+	}
+	*target = strconv.FormatInt(int64(d/unit), 10)
+	return nil
+}
+
+// durationOptionReason describes the values accepted by a duration option.
+func durationOptionReason(unit time.Duration, limit time.Duration) string {
+	name := "seconds"
+	if unit == time.Minute {
+		name = "minutes"
+	}
+	if limit == 0 {
+		return "must be a whole number of " + name + ", not negative"
+	}
+	return fmt.Sprintf("must be a whole number of %s from 0 to %d", name, limit/unit)
+}
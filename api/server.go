@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"reflect"
 )
 
 // GetServerOptionsResponse represents the response from the Satisfactory dedicated server
@@ -43,6 +44,34 @@ type ServerOptions struct {
 
 	// NetworkQuality sets the network quality mode for the server.
 	NetworkQuality string `json:"FG.NetworkQuality,omitempty"`
+
+	// Extra holds the options that have no field, such as those added by game updates or mods,
+	// keyed by their FG.* wire key. They are kept when decoding and sent back when encoding.
+	Extra map[string]string `json:"-"`
+}
+
+// serverOptionKeys are the wire keys of the ServerOptions fields.
+var serverOptionKeys = jsonKeys(reflect.TypeFor[ServerOptions]())
+
+// MarshalJSON encodes the options with their FG.* wire keys, including the Extra options.
+func (o ServerOptions) MarshalJSON() ([]byte, error) {
+	type plain ServerOptions
+	return marshalWithExtra(plain(o), o.Extra)
+}
+
+// UnmarshalJSON decodes options with their FG.* wire keys, keeping unknown keys in Extra.
+func (o *ServerOptions) UnmarshalJSON(data []byte) error {
+	type plain ServerOptions
+	if err := json.Unmarshal(data, (*plain)(o)); err != nil {
+		return err
+	}
+
+	extra, err := unmarshalExtra(data, serverOptionKeys)
+	if err != nil {
+		return err
+	}
+	o.Extra = extra
+	return nil
 }
 
 // ApplyServerOptionsRequest represents a request to apply new server options.
@@ -61,38 +90,22 @@ type ApplyServerOptionsRequestData struct {
 }
 
 // ApplyServerOptions applies new server configuration options to the Satisfactory dedicated server.
+// Options the server would ignore, such as "true" instead of "True", fail with an error matching
+// ErrInvalidServerOption without being sent. See TypedServerOptions.
 func (c *GoFactoryClient) ApplyServerOptions(ctx context.Context, options ServerOptions) error {
-	functionBody, err := json.Marshal(ApplyServerOptionsRequest{
-		Function: ApplyAdvancedGameSettingsFunction,
-		Data:     ApplyServerOptionsRequestData{ServerOptions: options},
-	})
-	if err != nil {
+	if err := options.Validate(); err != nil {
 		return err
 	}
 
-	request, err := c.CreatePostRequest(ApplyServerOptionsFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	err = c.SendPostRequest(ctx, request, functionBody)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := CallFunction[ApplyServerOptionsRequestData, Empty](ctx, c, ApplyServerOptionsFunction,
+		ApplyServerOptionsRequestData{ServerOptions: options})
+	return err
 }
 
 // GetServerOptions retrieves the current and pending server options
 // from the Satisfactory dedicated server.
 func (c *GoFactoryClient) GetServerOptions(ctx context.Context) (*GetServerOptionsData, error) {
-	optionsResponse, err := CreateAndSendPostRequest[GetServerOptionsResponse](ctx, c,
-		GetServerOptionsFunction,
-		CreateGenericFunctionBody(GetServerOptionsFunction))
-	if err != nil {
-		return nil, err
-	}
-	return &optionsResponse.Data, nil
+	return CallFunction[Empty, GetServerOptionsData](ctx, c, GetServerOptionsFunction, Empty{})
 }
 
 // QueryServerStateData represents the current state of the Satisfactory server.
@@ -134,20 +147,22 @@ type QueryServerStateData struct {
 // QueryServerStateResponse represents the response from the server when querying its state.
 type QueryServerStateResponse struct {
 	// Data contains the current server game state.
-	Data struct {
-		State QueryServerStateData `json:"serverGameState,omitempty"`
-	} `json:"data"`
+	Data ServerGameState `json:"data"`
+}
+
+// ServerGameState wraps the current server game state in the QueryServerState response.
+type ServerGameState struct {
+	// State is the current server game state.
+	State QueryServerStateData `json:"serverGameState,omitempty"`
 }
 
 // QueryServerState queries the current state of the Satisfactory server and returning all state data.
 func (c *GoFactoryClient) QueryServerState(ctx context.Context) (*QueryServerStateData, error) {
-	queryServerResponse, err := CreateAndSendPostRequest[QueryServerStateResponse](ctx, c,
-		QueryServerStateFunction,
-		CreateGenericFunctionBody(QueryServerStateFunction))
+	response, err := CallFunction[Empty, ServerGameState](ctx, c, QueryServerStateFunction, Empty{})
 	if err != nil {
 		return nil, err
 	}
-	return &queryServerResponse.Data.State, nil
+	return &response.State, nil
 }
 
 // SetAutoLoadSessionRequest represents a request to set the automatically loaded session during
@@ -168,25 +183,11 @@ type SetAutoLoadSessionRequestData struct {
 
 // SetAutoLoadSessionName sets the auto-load session name on the Satisfactory server.
 func (c *GoFactoryClient) SetAutoLoadSessionName(ctx context.Context, sessionName string) (bool, error) {
-	functionBody, err := json.Marshal(SetAutoLoadSessionRequest{
-		Function: SetAutoLoadSessionNameFunction,
-		Data: SetAutoLoadSessionRequestData{
-			SessionName: sessionName,
-		},
-	})
+	_, err := CallFunction[SetAutoLoadSessionRequestData, Empty](ctx, c, SetAutoLoadSessionNameFunction,
+		SetAutoLoadSessionRequestData{SessionName: sessionName})
 	if err != nil {
 		return false, err
 	}
-	request, err := c.CreatePostRequest(SetAutoLoadSessionNameFunction, functionBody)
-	if err != nil {
-		return false, err
-	}
-
-	err = c.SendPostRequest(ctx, request, functionBody)
-	if err != nil {
-		return false, err
-	}
-
 	return true, nil
 }
 
@@ -205,45 +206,47 @@ type RunCommandRequestData struct {
 	Command string `json:"command"`
 }
 
-// RunServerCommand sends a console command to the Satisfactory dedicated server for execution.
-func (c *GoFactoryClient) RunServerCommand(ctx context.Context, command string) error {
-	functionBody, err := json.Marshal(RunCommandRequest{
-		Function: RunCommandFunction,
-		Data: RunCommandRequestData{
-			Command: command,
-		},
-	})
-	if err != nil {
-		return err
-	}
-	request, err := c.CreatePostRequest(RunCommandFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	err = c.SendPostRequest(ctx, request, functionBody)
-	if err != nil {
-		return err
-	}
+// CommandResult holds the outcome of a console command run on the server.
+type CommandResult struct {
+	// Output is the console output of the command.
+	Output string `json:"commandResult"`
 
-	return nil
+	// ReturnValue reports whether the server ran the command.
+	ReturnValue bool `json:"returnValue"`
 }
 
-// ShutdownServer sends a request to shut down the Satisfactory dedicated server.
-func (c *GoFactoryClient) ShutdownServer(ctx context.Context) error {
-	functionBody := CreateGenericFunctionBody(ShutdownFunction)
+// RunCommandResponse represents the response from the server after running a console command.
+type RunCommandResponse struct {
+	// Data contains the result of the command.
+	Data CommandResult `json:"data"`
+}
 
-	request, err := c.CreatePostRequest(ShutdownFunction, functionBody)
-	if err != nil {
-		return err
+// RunCommand runs a console command on the Satisfactory dedicated server and returns its output.
+// Commands rejected by the client's CommandPolicy fail with ErrCommandNotAllowed without being sent.
+func (c *GoFactoryClient) RunCommand(ctx context.Context, command string) (CommandResult, error) {
+	if err := c.commandPolicy.Check(command); err != nil {
+		return CommandResult{}, err
 	}
 
-	err = c.SendPostRequest(ctx, request, functionBody)
+	result, err := CallFunction[RunCommandRequestData, CommandResult](ctx, c, RunCommandFunction,
+		RunCommandRequestData{Command: command})
 	if err != nil {
-		return err
+		return CommandResult{}, err
 	}
+	return *result, nil
+}
 
-	return nil
+// RunServerCommand sends a console command to the Satisfactory dedicated server for execution,
+// discarding its output. Use RunCommand to read the output.
+func (c *GoFactoryClient) RunServerCommand(ctx context.Context, command string) error {
+	_, err := c.RunCommand(ctx, command)
+	return err
+}
+
+// ShutdownServer sends a request to shut down the Satisfactory dedicated server.
+func (c *GoFactoryClient) ShutdownServer(ctx context.Context) error {
+	_, err := CallFunction[Empty, Empty](ctx, c, ShutdownFunction, Empty{})
+	return err
 }
 
 // RenameRequest represents a request to rename the Satisfactory server.
@@ -263,26 +266,7 @@ type RenameRequestData struct {
 
 // RenameServer renames the Satisfactory dedicated server to the specified serverName.
 func (c *GoFactoryClient) RenameServer(ctx context.Context, serverName string) error {
-	functionBody, err := json.Marshal(RenameRequest{
-		Function: RenameServerFunction,
-		Data: RenameRequestData{
-			ServerName: serverName,
-		},
-	})
-	if err != nil {
-		return err
-	}
-
-	req, err := c.CreatePostRequest(RenameServerFunction, functionBody)
-	if err != nil {
-		return err
-	}
-
-	var apiError APIError
-	err = c.SendPostRequest(ctx, req, &apiError)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	_, err := CallFunction[RenameRequestData, Empty](ctx, c, RenameServerFunction,
+		RenameRequestData{ServerName: serverName})
+	return err
 }
@@ -0,0 +1,166 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"testing"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+)
+
+func TestExtraOptionsRoundTrip(t *testing.T) {
+	extra := map[string]string{"FG.Mod.Option": "42"}
+
+	tests := []struct {
+		name string
+
+		// apply sends the extra options with a known option, and get reads the known option and the extra options back.
+		apply     func(ctx context.Context, client *api.GoFactoryClient) error
+		get       func(ctx context.Context, client *api.GoFactoryClient) (known string, extra map[string]string, err error)
+		stored    func(server *apitest.Server) map[string]string
+		wantKnown string
+	}{
+		{
+			name: "server options",
+			apply: func(ctx context.Context, client *api.GoFactoryClient) error {
+				return client.ApplyServerOptions(ctx, api.ServerOptions{AutosaveInterval: "600", Extra: extra})
+			},
+			get: func(ctx context.Context, client *api.GoFactoryClient) (string, map[string]string, error) {
+				options, err := client.GetServerOptions(ctx)
+				if err != nil {
+					return "", nil, err
+				}
+				return options.ServerOptions.AutosaveInterval, options.ServerOptions.Extra, nil
+			},
+			stored:    (*apitest.Server).ServerOptions,
+			wantKnown: "600",
+		},
+		{
+			name: "typed server options",
+			apply: func(ctx context.Context, client *api.GoFactoryClient) error {
+				return client.ApplyTypedServerOptions(ctx, api.TypedServerOptions{AutoPause: api.Ptr(false), Extra: extra})
+			},
+			get: func(ctx context.Context, client *api.GoFactoryClient) (string, map[string]string, error) {
+				options, err := client.GetServerOptions(ctx)
+				if err != nil {
+					return "", nil, err
+				}
+				typed, err := options.ServerOptions.Typed()
+				if err != nil || typed.AutoPause == nil {
+					return "", nil, err
+				}
+				return fmt.Sprint(*typed.AutoPause), typed.Extra, nil
+			},
+			stored:    (*apitest.Server).ServerOptions,
+			wantKnown: "false",
+		},
+		{
+			name: "advanced game settings",
+			apply: func(ctx context.Context, client *api.GoFactoryClient) error {
+				if err := client.CreateNewGame(ctx, api.CreateNewGameRequestData{SessionName: "Session"}); err != nil {
+					return err
+				}
+				return client.ApplyAdvancedGameSettings(ctx, api.AdvancedGameSettings{NoPower: "True", Extra: extra})
+			},
+			get: func(ctx context.Context, client *api.GoFactoryClient) (string, map[string]string, error) {
+				settings, err := client.GetAdvancedGameSettings(ctx)
+				if err != nil {
+					return "", nil, err
+				}
+				return settings.NoPower, settings.Extra, nil
+			},
+			stored:    (*apitest.Server).AdvancedGameSettings,
+			wantKnown: "True",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			client := server.NewClient(server.Claim("Test", "admin"))
+			ctx := context.Background()
+
+			if err := tt.apply(ctx, client); err != nil {
+				t.Fatalf("apply error = %v", err)
+			}
+			if got := tt.stored(server)["FG.Mod.Option"]; got != "42" {
+				t.Errorf("server stored FG.Mod.Option = %q, want %q", got, "42")
+			}
+
+			known, gotExtra, err := tt.get(ctx, client)
+			if err != nil {
+				t.Fatalf("get error = %v", err)
+			}
+			if known != tt.wantKnown {
+				t.Errorf("known option = %q, want %q", known, tt.wantKnown)
+			}
+			if !maps.Equal(gotExtra, extra) {
+				t.Errorf("Extra = %v, want %v", gotExtra, extra)
+			}
+		})
+	}
+}
+
+func TestExtraOptionsJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantKnown string
+		wantExtra map[string]string
+	}{
+		{
+			name:      "known options only",
+			data:      `{"FG.AutosaveInterval":"300"}`,
+			wantKnown: "300",
+		},
+		{
+			name:      "string extra option",
+			data:      `{"FG.AutosaveInterval":"300","FG.Mod.Option":"42"}`,
+			wantKnown: "300",
+			wantExtra: map[string]string{"FG.Mod.Option": "42"},
+		},
+		{
+			name:      "non-string extra option",
+			data:      `{"FG.Mod.Enabled":true,"FG.Mod.Ratio":0.5}`,
+			wantExtra: map[string]string{"FG.Mod.Enabled": "true", "FG.Mod.Ratio": "0.5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options api.ServerOptions
+			if err := json.Unmarshal([]byte(tt.data), &options); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if options.AutosaveInterval != tt.wantKnown || !maps.Equal(options.Extra, tt.wantExtra) {
+				t.Fatalf("decoded AutosaveInterval %q and Extra %v, want %q and %v",
+					options.AutosaveInterval, options.Extra, tt.wantKnown, tt.wantExtra)
+			}
+
+			data, err := json.Marshal(options)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			var again api.ServerOptions
+			if err := json.Unmarshal(data, &again); err != nil {
+				t.Fatalf("Unmarshal() of %s error = %v", data, err)
+			}
+			if again.AutosaveInterval != options.AutosaveInterval || !maps.Equal(again.Extra, options.Extra) {
+				t.Errorf("round trip through %s lost options", data)
+			}
+		})
+	}
+
+	// A field takes precedence over an extra option with the same key.
+	data, err := json.Marshal(api.ServerOptions{AutosaveInterval: "600", Extra: map[string]string{"FG.AutosaveInterval": "1"}})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if string(data) != `{"FG.AutosaveInterval":"600"}` {
+		t.Errorf("Marshal() = %s, want the field value", data)
+	}
+}
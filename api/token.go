@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrMalformedToken is returned by ParseToken when a token is not in the dedicated server's format.
+var ErrMalformedToken = errors.New("gofactory token error: malformed token")
+
+// tokenPrivilegeLevels maps the privilege levels encoded in tokens to the privilege constants
+// where they are spelled differently.
+var tokenPrivilegeLevels = map[string]string{
+	"APIToken": API_TOKEN_PRIVILEGE,
+}
+
+// TokenClaims holds the claims encoded in an authentication token issued by the dedicated server.
+// Tokens are a base64 encoded JSON payload followed by a dot and a hex signature. The claims are
+// decoded without checking the signature, which only the server can do; use VerifyToken for that.
+type TokenClaims struct {
+	// Privilege is the privilege level of the token, one of the privilege constants such as ADMINISTRATOR_PRIVILEGE.
+	Privilege Privilege
+
+	// Claims are all the claims in the token's payload, such as "pl" for the privilege level.
+	Claims map[string]any
+
+	// Signature is the hex signature of the token.
+	Signature string
+}
+
+// ParseToken decodes the claims of an authentication token without contacting the server.
+func ParseToken(token string) (*TokenClaims, error) {
+	payload, signature, found := strings.Cut(strings.TrimSpace(token), ".")
+	if !found || len(payload) == 0 || len(signature) == 0 {
+		return nil, fmt.Errorf("%w: expected a payload and a signature separated by a dot", ErrMalformedToken)
+	}
+
+	data, err := decodeTokenPayload(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot decode payload: %w", ErrMalformedToken, err)
+	}
+
+	claims := make(map[string]any)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil {
+		return nil, fmt.Errorf("%w: cannot decode claims: %w", ErrMalformedToken, err)
+	}
+
+	privilege, _ := claims["pl"].(string)
+	if known, ok := tokenPrivilegeLevels[privilege]; ok {
+		privilege = known
+	}
+
+	return &TokenClaims{
+		Privilege: Privilege(privilege),
+		Claims:    claims,
+		Signature: signature,
+	}, nil
+}
+
+// decodeTokenPayload decodes the base64 payload of a token, accepting padded, unpadded and URL safe encodings.
+func decodeTokenPayload(payload string) ([]byte, error) {
+	var err error
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		var data []byte
+		if data, err = encoding.DecodeString(payload); err == nil {
+			return data, nil
+		}
+	}
+	return nil, err
+}
+
+// tokenPrivilege returns the privilege level of a token: the one encoded in it, NOT_AUTHENTICATED_PRIVILEGE
+// for an empty token, or fallback if the token cannot be decoded.
+func tokenPrivilege(token string, fallback string) string {
+	if len(token) == 0 {
+		return NOT_AUTHENTICATED_PRIVILEGE
+	}
+	if claims, err := ParseToken(token); err == nil && len(claims.Privilege) != 0 {
+		return claims.Privilege.String()
+	}
+	return fallback
+}
+
+// VerifyToken asks the server whether the client's token is valid, and returns its decoded claims.
+// An invalid, expired or revoked token fails with an error matching ErrInvalidToken, even if the client
+// has a CredentialProvider, which is not used to authenticate again.
+func (c *GoFactoryClient) VerifyToken(ctx context.Context) (*TokenClaims, error) {
+	_, err := CallFunction[Empty, Empty](ctx, c, VerifyAuthTokenFunction, Empty{})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := ParseToken(c.Token())
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,149 @@
+// Package tracing provides optional OpenTelemetry tracing for GoFactoryClient calls and lightweight UDP queries.
+//
+// Each API call becomes a client span named after the API function, started from the span in the
+// context passed to the call:
+//
+//	tracer := tracing.NewTracer()
+//	client := api.NewClient(url, api.WithAuthToken(token), api.WithInterceptors(tracer.Interceptor()))
+package tracing
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer used for spans.
+const ScopeName = "github.com/alchemicalkube/gofactory/api/tracing"
+
+// PollServerStateSpanName is the name of the spans emitted for lightweight UDP queries.
+const PollServerStateSpanName = "PollServerState"
+
+// Attribute keys set on spans, in addition to the OpenTelemetry semantic conventions.
+const (
+	// FunctionKey is the API function called.
+	FunctionKey = attribute.Key("gofactory.function")
+
+	// PrivilegeKey is the privilege level the client believes its token has.
+	PrivilegeKey = attribute.Key("gofactory.privilege")
+
+	// ErrorCodeKey is the error code returned by the server, or the kind of failure.
+	ErrorCodeKey = attribute.Key("gofactory.error_code")
+
+	// AttemptsKey is the number of times the request was sent, including retries.
+	AttemptsKey = attribute.Key("gofactory.attempts")
+)
+
+// Tracer emits spans for GoFactoryClient calls and UDP queries. It is safe for concurrent use.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// Option configures a Tracer created with NewTracer.
+type Option func(*tracerOptions)
+
+// tracerOptions collects the settings applied by each Option before the tracer is built.
+type tracerOptions struct {
+	provider trace.TracerProvider
+}
+
+// WithTracerProvider sets the provider spans are created from. Defaults to the global provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *tracerOptions) {
+		o.provider = provider
+	}
+}
+
+// NewTracer creates a Tracer configured with the given options.
+func NewTracer(opts ...Option) *Tracer {
+	o := tracerOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.provider == nil {
+		o.provider = otel.GetTracerProvider()
+	}
+
+	return &Tracer{
+		tracer: o.provider.Tracer(ScopeName, trace.WithInstrumentationVersion(api.Version)),
+	}
+}
+
+// Interceptor returns an api.Interceptor that emits a span for every call made by a client.
+// The span is a child of the span in the call's context, and is passed on to later interceptors.
+func (t *Tracer) Interceptor() api.Interceptor {
+	return func(ctx context.Context, call *api.Call, next api.Invoker) error {
+		attributes := []attribute.KeyValue{
+			FunctionKey.String(call.Function),
+			PrivilegeKey.String(call.Privilege.String()),
+		}
+		if call.Request != nil && call.Request.URL != nil {
+			attributes = append(attributes, attribute.String("url.full", call.Request.URL.String()))
+			attributes = append(attributes, serverAttributes(call.Request.URL.Host)...)
+		}
+
+		ctx, span := t.tracer.Start(ctx, call.Function,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(attributes...),
+		)
+		defer span.End()
+
+		err := next(ctx, call)
+
+		span.SetAttributes(AttemptsKey.Int(call.Attempts))
+		if call.StatusCode != 0 {
+			span.SetAttributes(attribute.Int("http.response.status_code", call.StatusCode))
+		}
+		if err != nil {
+			span.SetAttributes(ErrorCodeKey.String(api.ErrorCodeOf(err)))
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+
+		return err
+	}
+}
+
+// Instrument adds the tracer's interceptor to client.
+func (t *Tracer) Instrument(client *api.GoFactoryClient) {
+	client.Use(t.Interceptor())
+}
+
+// SendUDPQuery calls api.SendUDPQuery inside a span that is a child of the span in ctx.
+func (t *Tracer) SendUDPQuery(ctx context.Context, server string, request []byte, maxRetries int, retryDelay time.Duration) ([]byte, error) {
+	_, span := t.tracer.Start(ctx, PollServerStateSpanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(serverAttributes(server)...),
+	)
+	defer span.End()
+
+	response, err := api.SendUDPQuery(server, request, maxRetries, retryDelay)
+	if err != nil {
+		span.SetAttributes(ErrorCodeKey.String(api.ErrorCodeTransport))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return response, err
+}
+
+// serverAttributes returns the server.address and server.port attributes for a host:port.
+func serverAttributes(hostPort string) []attribute.KeyValue {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return []attribute.KeyValue{attribute.String("server.address", hostPort)}
+	}
+
+	attributes := []attribute.KeyValue{attribute.String("server.address", host)}
+	if portNumber, err := strconv.Atoi(port); err == nil {
+		attributes = append(attributes, attribute.Int("server.port", portNumber))
+	}
+	return attributes
+}
@@ -0,0 +1,217 @@
+package tracing_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/alchemicalkube/gofactory/api/apitest"
+	"github.com/alchemicalkube/gofactory/api/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracer returns a tracer recording its spans, and the context of a parent span to start calls from.
+func newTracer(t *testing.T) (*tracing.Tracer, *tracetest.SpanRecorder, context.Context, trace.SpanContext) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	t.Cleanup(func() { parent.End() })
+	return tracing.NewTracer(tracing.WithTracerProvider(provider)), recorder, ctx, parent.SpanContext()
+}
+
+// endedSpan returns the only span the recorder saw ended.
+func endedSpan(t *testing.T, recorder *tracetest.SpanRecorder) sdktrace.ReadOnlySpan {
+	t.Helper()
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d ended spans, want 1", len(spans))
+	}
+	return spans[0]
+}
+
+// spanAttributes returns the attributes of a span by key.
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+// checkSpan checks the parent, kind, attributes and status shared by every span.
+func checkSpan(t *testing.T, span sdktrace.ReadOnlySpan, name string, parent trace.SpanContext, want map[attribute.Key]string, wantErr bool) {
+	t.Helper()
+
+	if span.Name() != name {
+		t.Errorf("span name = %q, want %q", span.Name(), name)
+	}
+	if span.Parent().SpanID() != parent.SpanID() || span.SpanContext().TraceID() != parent.TraceID() {
+		t.Errorf("span parent = %s, want %s", span.Parent().SpanID(), parent.SpanID())
+	}
+	if span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span kind = %s, want %s", span.SpanKind(), trace.SpanKindClient)
+	}
+
+	attributes := spanAttributes(span)
+	for key, value := range want {
+		if got, ok := attributes[key]; !ok || got.Emit() != value {
+			t.Errorf("attribute %s = %q, want %q", key, got.Emit(), value)
+		}
+	}
+	if _, ok := attributes[tracing.ErrorCodeKey]; ok != wantErr {
+		t.Errorf("span has an error code %v, want %v", ok, wantErr)
+	}
+	if (span.Status().Code == codes.Error) != wantErr {
+		t.Errorf("span status = %s, want an error %v", span.Status().Code, wantErr)
+	}
+}
+
+func TestInterceptorSpans(t *testing.T) {
+	tests := []struct {
+		name string
+
+		// call makes the traced call with a client holding token.
+		call       func(ctx context.Context, server *apitest.Server, client *api.GoFactoryClient) error
+		token      func(server *apitest.Server) string
+		function   string
+		wantStatus string
+		wantCode   string
+	}{
+		{
+			name: "successful call",
+			call: func(ctx context.Context, _ *apitest.Server, client *api.GoFactoryClient) error {
+				_, err := client.QueryServerState(ctx)
+				return err
+			},
+			token:      func(server *apitest.Server) string { return server.IssueToken(api.ADMINISTRATOR_PRIVILEGE) },
+			function:   api.QueryServerStateFunction,
+			wantStatus: "200",
+		},
+		{
+			name: "error code",
+			call: func(ctx context.Context, _ *apitest.Server, client *api.GoFactoryClient) error {
+				return client.PasswordLogin(ctx, api.ADMINISTRATOR_PRIVILEGE, "wrong")
+			},
+			token:      func(*apitest.Server) string { return "" },
+			function:   api.PasswordLoginFunction,
+			wantStatus: "401",
+			wantCode:   api.ErrorCodeWrongPassword,
+		},
+		{
+			name: "server error",
+			call: func(ctx context.Context, server *apitest.Server, client *api.GoFactoryClient) error {
+				server.FailNext(1, http.StatusServiceUnavailable)
+				return client.RenameServer(ctx, "Renamed")
+			},
+			token:      func(server *apitest.Server) string { return server.IssueToken(api.API_TOKEN_PRIVILEGE) },
+			function:   api.RenameServerFunction,
+			wantStatus: "503",
+			wantCode:   "http_503",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := apitest.NewServer()
+			defer server.Close()
+			server.Claim("Test", "admin")
+
+			tracer, recorder, ctx, parent := newTracer(t)
+			client := server.NewClient(tt.token(server), api.WithInterceptors(tracer.Interceptor()))
+			privilege := client.Privilege()
+
+			err := tt.call(ctx, server, client)
+			if (err != nil) != (len(tt.wantCode) != 0) || api.ErrorCodeOf(err) != tt.wantCode {
+				t.Fatalf("call error = %v with code %q, want code %q", err, api.ErrorCodeOf(err), tt.wantCode)
+			}
+
+			host, port, _ := net.SplitHostPort(server.URL[len("https://"):])
+			want := map[attribute.Key]string{
+				tracing.FunctionKey:         tt.function,
+				tracing.PrivilegeKey:        privilege.String(),
+				tracing.AttemptsKey:         "1",
+				"server.address":            host,
+				"server.port":               port,
+				"http.response.status_code": tt.wantStatus,
+			}
+			if len(tt.wantCode) != 0 {
+				want[tracing.ErrorCodeKey] = tt.wantCode
+			}
+			checkSpan(t, endedSpan(t, recorder), tt.function, parent, want, len(tt.wantCode) != 0)
+		})
+	}
+}
+
+func TestSendUDPQuerySpan(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	go func() {
+		buffer := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo(buffer[:n], addr)
+		}
+	}()
+	host, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		wantErr    bool
+	}{
+		{name: "response", maxRetries: 3},
+		// Without any attempt the query fails at once, like a server that never answers.
+		{name: "no response", maxRetries: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracer, recorder, ctx, parent := newTracer(t)
+
+			response, err := tracer.SendUDPQuery(ctx, conn.LocalAddr().String(), []byte("poll"), tt.maxRetries, time.Millisecond)
+			if (err != nil) != tt.wantErr || (err == nil && string(response) != "poll") {
+				t.Fatalf("SendUDPQuery() = %q, %v, want an error %v", response, err, tt.wantErr)
+			}
+
+			want := map[attribute.Key]string{
+				"server.address": host,
+				"server.port":    port,
+			}
+			if tt.wantErr {
+				want[tracing.ErrorCodeKey] = api.ErrorCodeTransport
+			}
+			checkSpan(t, endedSpan(t, recorder), tracing.PollServerStateSpanName, parent, want, tt.wantErr)
+		})
+	}
+}
+
+func TestInterceptorWithoutParent(t *testing.T) {
+	server := apitest.NewServer()
+	defer server.Close()
+
+	tracer, recorder, _, _ := newTracer(t)
+	client := server.NewClient("", api.WithInterceptors(tracer.Interceptor()))
+	if _, err := client.GetServerHealth(context.Background(), "test"); err != nil {
+		t.Fatalf("GetServerHealth() error = %v", err)
+	}
+
+	if span := endedSpan(t, recorder); span.Parent().IsValid() {
+		t.Errorf("span parent = %s, want a root span for a context without one", span.Parent().SpanID())
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"io"
+	"io/fs"
+)
+
+// ProgressFunc is called as a save game file is transferred, with the number of bytes of the file
+// transferred so far and its total size, or -1 if the size is not known in advance.
+type ProgressFunc func(transferred int64, total int64)
+
+// progressReader reports the bytes read from reader to a ProgressFunc.
+type progressReader struct {
+	reader      io.Reader
+	progress    ProgressFunc
+	transferred int64
+	total       int64
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.transferred += int64(n)
+		r.progress(r.transferred, r.total)
+	}
+	return n, err
+}
+
+// withProgress wraps reader so that reads are reported to progress, if it is not nil.
+func withProgress(reader io.Reader, total int64, progress ProgressFunc) io.Reader {
+	if progress == nil {
+		return reader
+	}
+	return &progressReader{reader: reader, progress: progress, total: total}
+}
+
+// readerSize returns the number of bytes left to read from reader if it is an in-memory reader
+// such as *bytes.Reader, or a regular *os.File, and -1 otherwise.
+func readerSize(reader io.Reader) int64 {
+	switch r := reader.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len())
+	case interface {
+		Stat() (fs.FileInfo, error)
+		io.Seeker
+	}:
+		info, err := r.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil || offset > info.Size() {
+			return -1
+		}
+		return info.Size() - offset
+	}
+	return -1
+}
+
+// byteCounter is an io.Writer that only counts the bytes written to it.
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
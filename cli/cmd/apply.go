@@ -0,0 +1,444 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	manifestFileFlag string
+	dryRunFlag       bool
+)
+
+// serverManifest is the desired configuration of a server, read by the apply command.
+// Fields that are not set are left unchanged. Passwords may reference environment variables,
+// such as ${GF_ADMIN_PASSWORD}, so that the manifest can be kept in git.
+type serverManifest struct {
+	ServerName           string         `yaml:"serverName"`
+	AdminPassword        *string        `yaml:"adminPassword"`
+	ClientPassword       *string        `yaml:"clientPassword"`
+	AutoLoadSessionName  string         `yaml:"autoLoadSessionName"`
+	ServerOptions        map[string]any `yaml:"serverOptions"`
+	AdvancedGameSettings map[string]any `yaml:"advancedGameSettings"`
+}
+
+// planStep is a change the apply command makes to the server.
+type planStep struct {
+	// title describes the change, such as "server name".
+	title string
+
+	// current and desired are the values before and after the change. They are not printed for sensitive steps.
+	current   string
+	desired   string
+	sensitive bool
+
+	// changes are the options changed by the step, printed instead of its values.
+	changes []optionChange
+
+	// unchecked explains why the current value was not compared, in which case the step may not be needed.
+	unchecked string
+
+	apply func() error
+}
+
+var applyCommand = &cobra.Command{
+	Use:   "apply",
+	Short: "bring the server in line with a manifest file",
+	Long: "reads the desired server name, passwords, server options, advanced game settings and auto-load session " +
+		"from a YAML manifest, prints the changes needed and makes them once confirmed.\n\n" +
+		"The passwords are checked by logging in with them, which a dry run skips. " +
+		"Advanced game settings need a running session; a dry run without one lists them as unknown. " +
+		"An empty clientPassword removes the client password. " +
+		"Changing the admin password replaces the token, and the new one is stored in the cli configuration.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		applyManifest(manifestFileFlag)
+	},
+}
+
+func applyManifest(path string) {
+	manifest, err := readManifest(path)
+	if err != nil {
+		Logger.Fatal("cannot read the manifest", Logger.Args("error", err))
+	}
+
+	plan, err := planManifest(manifest)
+	if err != nil {
+		Logger.Fatal("cannot plan the changes", Logger.Args("error", err))
+	}
+
+	if len(plan) == 0 {
+		Logger.Info("the server already matches the manifest")
+		return
+	}
+
+	printPlan(plan)
+	if dryRunFlag {
+		return
+	}
+
+	if !yesFlag {
+		confirmed, err := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			Show(fmt.Sprintf("Apply %d changes?", len(plan)))
+		if err != nil {
+			Logger.Fatal("cannot confirm", Logger.Args("error", err))
+		}
+		if !confirmed {
+			Logger.Info("no changes were made")
+			return
+		}
+	}
+
+	for i, step := range plan {
+		if err := step.apply(); err != nil {
+			Logger.Fatal("cannot change "+step.title, Logger.Args(
+				"error", err,
+				"applied", fmt.Sprintf("%d of %d changes", i, len(plan)),
+			))
+		}
+		Logger.Info("changed " + step.title)
+	}
+	Logger.Info("the server matches the manifest", Logger.Args("changes", len(plan)))
+}
+
+// readManifest reads and checks a server manifest, expanding environment variables in its passwords.
+func readManifest(path string) (*serverManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read manifest file: %w", err)
+	}
+
+	var manifest serverManifest
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("cannot decode manifest file %s: %w", path, err)
+	}
+
+	if manifest.AdminPassword != nil {
+		*manifest.AdminPassword = os.ExpandEnv(*manifest.AdminPassword)
+		if len(*manifest.AdminPassword) == 0 {
+			return nil, errors.New("adminPassword cannot be empty, check that its environment variable is set")
+		}
+	}
+	// An empty clientPassword removes the password, which must not happen because a variable is not set.
+	if manifest.ClientPassword != nil {
+		raw := *manifest.ClientPassword
+		*manifest.ClientPassword = os.ExpandEnv(raw)
+		if len(raw) != 0 && len(*manifest.ClientPassword) == 0 {
+			return nil, errors.New("clientPassword expands to an empty password, check that its environment variable " +
+				"is set, or set it to \"\" to remove the password")
+		}
+	}
+
+	return &manifest, nil
+}
+
+// planManifest compares the manifest with the server, and returns the steps needed to make them match.
+func planManifest(manifest *serverManifest) ([]planStep, error) {
+	var plan []planStep
+
+	state, err := client.QueryServerState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot query the server state: %w", err)
+	}
+
+	if len(manifest.ServerName) != 0 {
+		current, ok := queryServerName()
+		if !ok {
+			Logger.Warn("cannot compare the server name, as the server did not answer the lightweight query, "+
+				"rename it with gofactory server rename if needed", Logger.Args("desired", manifest.ServerName))
+		} else if current != manifest.ServerName {
+			name := manifest.ServerName
+			plan = append(plan, planStep{
+				title:   "server name",
+				current: current,
+				desired: name,
+				apply: func() error {
+					return client.RenameServer(ctx, name)
+				},
+			})
+		}
+	}
+
+	if manifest.AdminPassword != nil {
+		password := *manifest.AdminPassword
+		step, err := planPassword("admin password", api.ADMINISTRATOR_PRIVILEGE, password, func() error {
+			if err := client.SetAdminPassword(ctx, password); err != nil {
+				return err
+			}
+			storeReplacementToken(client.Token())
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			plan = append(plan, *step)
+		}
+	}
+
+	if manifest.ClientPassword != nil {
+		password := *manifest.ClientPassword
+		step, err := planPassword("client password", api.CLIENT_PRIVILEGE, password, func() error {
+			return client.SetClientPassword(ctx, password)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			plan = append(plan, *step)
+		}
+	}
+
+	if len(manifest.ServerOptions) != 0 {
+		step, err := planServerOptions(manifest.ServerOptions)
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			plan = append(plan, *step)
+		}
+	}
+
+	if len(manifest.AdvancedGameSettings) != 0 {
+		step, err := planAdvancedGameSettings(manifest.AdvancedGameSettings, state.IsGameRunning)
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			plan = append(plan, *step)
+		}
+	}
+
+	if len(manifest.AutoLoadSessionName) != 0 && manifest.AutoLoadSessionName != state.AutoLoadSessionName {
+		session := manifest.AutoLoadSessionName
+		plan = append(plan, planStep{
+			title:   "auto-load session",
+			current: state.AutoLoadSessionName,
+			desired: session,
+			apply: func() error {
+				_, err := client.SetAutoLoadSessionName(ctx, session)
+				return err
+			},
+		})
+	}
+
+	return plan, nil
+}
+
+// planServerOptions returns the step that applies the desired server options, or nil if they are already set.
+// Options pending a restart count as set.
+func planServerOptions(values map[string]any) (*planStep, error) {
+	desired, err := serverOptionsFromValues(values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid serverOptions: %w", err)
+	}
+	desiredOptions, err := toOptionMap(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := client.GetServerOptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the server options: %w", err)
+	}
+	current, err := toOptionMap(options.ServerOptions)
+	if err != nil {
+		return nil, err
+	}
+	pending, err := toOptionMap(options.PendingServerOptions)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range pending {
+		current[key] = value
+	}
+
+	changes := diffOptions(current, desiredOptions)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	return &planStep{
+		title:   "server options",
+		changes: changes,
+		apply: func() error {
+			var update api.ServerOptions
+			if err := fromOptionMap(changedOptions(changes), &update); err != nil {
+				return err
+			}
+			return client.ApplyServerOptions(ctx, update)
+		},
+	}, nil
+}
+
+// planAdvancedGameSettings returns the step that applies the desired advanced game settings, or nil if they
+// are already set. Advanced game settings belong to the running session, and cannot be read without one,
+// so a dry run returns the step unchecked instead.
+func planAdvancedGameSettings(values map[string]any, gameRunning bool) (*planStep, error) {
+	desired, err := optionStrings(values)
+	if err != nil {
+		return nil, fmt.Errorf("invalid advancedGameSettings: %w", err)
+	}
+
+	if !gameRunning {
+		if !dryRunFlag {
+			return nil, errors.New("advanced game settings can only be changed while a session is running")
+		}
+		return &planStep{
+			title:     "advanced game settings",
+			unchecked: "no session is running, start one before applying them",
+		}, nil
+	}
+
+	settings, err := client.GetAdvancedGameSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get the advanced game settings: %w", err)
+	}
+	current, err := toOptionMap(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffOptions(current, desired)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	return &planStep{
+		title:   "advanced game settings",
+		changes: changes,
+		apply: func() error {
+			var update api.AdvancedGameSettings
+			if err := fromOptionMap(changedOptions(changes), &update); err != nil {
+				return err
+			}
+			return client.ApplyAdvancedGameSettings(ctx, update)
+		},
+	}, nil
+}
+
+// planPassword returns the step that sets the password of the privilege, or nil if it is already set.
+// Checking the password logs in with it, so a dry run returns the step unchecked instead.
+func planPassword(title string, privilege string, password string, apply func() error) (*planStep, error) {
+	step := &planStep{title: title, sensitive: true, apply: apply}
+	if dryRunFlag {
+		step.unchecked = "not checked in a dry run"
+		return step, nil
+	}
+
+	matches, err := passwordMatches(privilege, password)
+	if err != nil {
+		return nil, err
+	}
+	if matches {
+		return nil, nil
+	}
+	return step, nil
+}
+
+// passwordMatches reports whether password is the current password of the privilege, by logging in with it
+// on a copy of the client. An empty client password matches when passwordless client logins are possible.
+func passwordMatches(privilege string, password string) (bool, error) {
+	probe := client.WithToken("")
+
+	var err error
+	if len(password) == 0 {
+		err = probe.PasswordlessLogin(ctx, privilege)
+	} else {
+		err = probe.PasswordLogin(ctx, privilege, password)
+	}
+
+	switch {
+	case err == nil:
+		return probe.Privilege() == api.Privilege(privilege), nil
+	case errors.Is(err, api.ErrWrongPassword), errors.Is(err, api.ErrPasswordlessLoginNotPossible):
+		return false, nil
+	default:
+		return false, fmt.Errorf("cannot check the %s password: %w", privilege, err)
+	}
+}
+
+// queryServerName returns the server name reported by the lightweight query API on the port of the server url,
+// as the HTTPS API does not report it.
+func queryServerName() (string, bool) {
+	serverURL, err := url.Parse(serverUrl)
+	if err != nil {
+		return "", false
+	}
+	port := serverURL.Port()
+	if len(port) == 0 {
+		port = "7777"
+	}
+
+	request, err := api.BuildEnvelope(uint64(time.Now().UnixNano()))
+	if err != nil {
+		return "", false
+	}
+	response, err := api.SendUDPQuery(net.JoinHostPort(serverURL.Hostname(), port), request, 1, 0)
+	if err != nil {
+		Logger.Trace("cannot query the server name", Logger.Args("error", err))
+		return "", false
+	}
+
+	state, err := api.ParseServerStateResponse(response)
+	if err != nil || int(state.ServerNameLength) > len(state.ServerName) {
+		return "", false
+	}
+	return string(state.ServerName[:state.ServerNameLength]), true
+}
+
+// printPlan prints the plan as "~ title: current -> desired" lines, and "? title" lines for unchecked steps.
+func printPlan(plan []planStep) {
+	pterm.Println("gofactory will make the following changes:")
+	pterm.Println()
+
+	unchecked := 0
+	for _, step := range plan {
+		switch {
+		case len(step.unchecked) != 0:
+			unchecked++
+			pterm.Printfln("%s %s: %s", pterm.FgGray.Sprint("?"), step.title, pterm.FgGray.Sprintf("(unknown, %s)", step.unchecked))
+		case step.sensitive:
+			pterm.Printfln("%s %s: %s", pterm.FgYellow.Sprint("~"), step.title, pterm.FgGray.Sprint("(sensitive)"))
+		case len(step.changes) != 0:
+			pterm.Printfln("%s %s", pterm.FgYellow.Sprint("~"), step.title)
+			printOptionChanges(step.changes)
+		default:
+			current := step.current
+			if len(current) == 0 {
+				current = "(not set)"
+			}
+			pterm.Printfln("%s %s: %s -> %s", pterm.FgYellow.Sprint("~"), step.title,
+				pterm.FgRed.Sprint(current), pterm.FgGreen.Sprint(step.desired))
+		}
+	}
+
+	pterm.Println()
+	if unchecked != 0 {
+		pterm.Printfln("Plan: %d to change, %d unknown.", len(plan)-unchecked, unchecked)
+		return
+	}
+	pterm.Printfln("Plan: %d to change.", len(plan))
+}
+
+func init() {
+	Root.AddCommand(applyCommand)
+
+	applyCommand.Flags().StringVarP(&manifestFileFlag, "file", "f", "", "YAML manifest of the desired server configuration")
+	applyCommand.Flags().BoolVar(&dryRunFlag, "dry-run", false, "print the changes without making them or checking the passwords")
+	applyCommand.Flags().BoolVarP(&yesFlag, "yes", "y", false, "do not ask for confirmation")
+	_ = applyCommand.MarkFlagRequired("file")
+}
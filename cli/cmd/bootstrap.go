@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	adminPasswordFlag    string
+	clientPasswordFlag   string
+	optionsFileFlag      string
+	newGameFlag          string
+	mapNameFlag          string
+	startingLocationFlag string
+	skipOnboardingFlag   bool
+	saveFileFlag         string
+	saveNameFlag         string
+	autoLoadFlag         string
+)
+
+var bootstrapCommand = &cobra.Command{
+	Use:   "bootstrap",
+	Short: "claim and set up a freshly installed server",
+	Long: "claims the server, sets the client password, applies server options, creates a new game or uploads a save, " +
+		"and sets the auto-load session. Steps that are already done are skipped, so it can be run again after a failure. " +
+		"The administrator token is printed once at the end and stored in the cli configuration.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		bootstrapServer()
+	},
+}
+
+func bootstrapServer() {
+	config := api.BootstrapConfig{
+		ServerName:          serverNameFlag,
+		AdminPassword:       adminPasswordFlag,
+		ClientPassword:      clientPasswordFlag,
+		AutoLoadSessionName: autoLoadFlag,
+	}
+	if len(config.AdminPassword) == 0 {
+		config.AdminPassword = os.Getenv(ENV_GF_ADMIN_PASSWORD)
+	}
+
+	if len(optionsFileFlag) != 0 {
+		options, err := readServerOptionsFile(optionsFileFlag)
+		if err != nil {
+			Logger.Fatal("cannot read the server options", Logger.Args("error", err))
+		}
+		config.ServerOptions = options
+	}
+
+	if len(newGameFlag) != 0 {
+		config.NewGame = &api.CreateNewGameRequestData{
+			SessionName:      newGameFlag,
+			MapName:          mapNameFlag,
+			StartingLocation: startingLocationFlag,
+			BSkipOnboarding:  skipOnboardingFlag,
+		}
+	}
+
+	if len(saveFileFlag) != 0 {
+		file, err := os.Open(saveFileFlag)
+		if err != nil {
+			Logger.Fatal("cannot open the save file", Logger.Args("error", err))
+		}
+		defer file.Close()
+
+		filename := filepath.Base(saveFileFlag)
+		saveName := saveNameFlag
+		if len(saveName) == 0 {
+			saveName = strings.TrimSuffix(filename, filepath.Ext(filename))
+		}
+		config.SaveGame = &api.BootstrapSaveGame{
+			File:     file,
+			Filename: filename,
+			Settings: api.UploadSaveGameDataRequest{SaveName: saveName, LoadImmediately: true},
+		}
+	}
+
+	config.OnStep = func(result api.BootstrapStepResult) {
+		if result.Skipped {
+			Logger.Info("skipped "+string(result.Step), Logger.Args("reason", result.Reason))
+			return
+		}
+		Logger.Info("done: " + string(result.Step))
+	}
+
+	result, err := client.Bootstrap(ctx, config)
+	if err != nil {
+		Logger.Fatal("cannot bootstrap the server", Logger.Args("error", err))
+	}
+
+	Logger.Info("server bootstrapped", Logger.Args(
+		"server name", config.ServerName,
+		"token", result.AuthenticationToken,
+	))
+
+	path, err := saveToken(result.AuthenticationToken)
+	if err != nil {
+		Logger.Warn("cannot store the administrator token, use the token printed above", Logger.Args("error", err))
+		return
+	}
+	if !warnTokenOverridden(path) {
+		Logger.Info("the administrator token was stored", Logger.Args("config", path))
+	}
+}
+
+func init() {
+	Root.AddCommand(bootstrapCommand)
+
+	bootstrapCommand.Flags().StringVarP(&serverNameFlag, "name", "n", "", "name to claim the server with")
+	bootstrapCommand.Flags().StringVar(&adminPasswordFlag, "admin-password", "", "admin password to claim the server with, defaults to $"+ENV_GF_ADMIN_PASSWORD)
+	bootstrapCommand.Flags().StringVar(&clientPasswordFlag, "client-password", "", "client protection password to set")
+	bootstrapCommand.Flags().StringVar(&optionsFileFlag, "options-file", "", "YAML or JSON file of FG.* server options to apply")
+	bootstrapCommand.Flags().StringVar(&newGameFlag, "new-game", "", "session name of a new game to create")
+	bootstrapCommand.Flags().StringVar(&mapNameFlag, "map", "", "map of the new game, defaults to the default level")
+	bootstrapCommand.Flags().StringVar(&startingLocationFlag, "starting-location", "", "starting location of the new game, defaults to a random one")
+	bootstrapCommand.Flags().BoolVar(&skipOnboardingFlag, "skip-onboarding", false, "skip the onboarding of the new game")
+	bootstrapCommand.Flags().StringVar(&saveFileFlag, "save-file", "", "save file to upload and load instead of creating a new game")
+	bootstrapCommand.Flags().StringVar(&saveNameFlag, "save-name", "", "name of the uploaded save, defaults to the file name")
+	bootstrapCommand.Flags().StringVar(&autoLoadFlag, "auto-load", "", "session to load when the server starts, defaults to the game's session")
+	bootstrapCommand.MarkFlagsMutuallyExclusive("new-game", "save-file")
+	_ = bootstrapCommand.MarkFlagRequired("name")
+}
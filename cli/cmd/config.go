@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// cliConfig is the configuration stored by the cli between runs. The GF_URL and GF_TOKEN
+// environment variables take precedence over it.
+type cliConfig struct {
+	URL   string `json:"url,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+// configPath returns the location of the cli configuration file, next to the known hosts file.
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gofactory", "config.json"), nil
+}
+
+// loadConfig reads the cli configuration file. A missing file is an empty configuration.
+func loadConfig() (*cliConfig, error) {
+	path, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &cliConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot read config file: %w", err)
+	}
+
+	var config cliConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("cannot decode config file %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// save writes the configuration file, readable only by the current user as it holds a token.
+func (c *cliConfig) save() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("cannot create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return "", fmt.Errorf("cannot write config file: %w", err)
+	}
+	return path, nil
+}
@@ -63,7 +63,7 @@ func passwordlessLogin(privilege string) {
 		Logger.Fatal("error with passwordless login", Logger.Args("error", err))
 	}
 
-	if len(client.Token) == 0 {
+	if len(client.Token()) == 0 {
 		Logger.Fatal("api returned an empty token. are you sure it is not claimed or no client protection password is enabled?")
 	}
 
@@ -71,9 +71,9 @@ func passwordlessLogin(privilege string) {
 
 	Logger.Info("server response success", Logger.Args(
 		"privilege", privilegeFlag,
-		"new token", client.Token,
+		"new token", client.Token(),
 		"warning", pterm.NewStyle(pterm.FgWhite, pterm.BgYellow).
-			Sprint(fmt.Sprintf("if you wish to use this with gofactory-cli, replace your %s environment variable", ENV_GF_TOKEN)),
+			Sprint(fmt.Sprintf("if you wish to use this with gofactory-cli, replace your %s environment variable or run gofactory token generate", ENV_GF_TOKEN)),
 	))
 }
 
@@ -103,7 +103,7 @@ func passwordLogin(password string, privilege string) {
 		Logger.Fatal("password command error", Logger.Args("error", err))
 	}
 
-	if client.Token == "" {
+	if client.Token() == "" {
 		Logger.Fatal("api returned an empty token. is your password correct?")
 	}
 
@@ -111,9 +111,9 @@ func passwordLogin(password string, privilege string) {
 
 	Logger.Info("server response success", Logger.Args(
 		"privilege", privilegeFlag,
-		"new token", client.Token,
+		"new token", client.Token(),
 		"warning", pterm.NewStyle(pterm.FgWhite, pterm.BgYellow).
-			Sprint(fmt.Sprintf("if you wish to use this with gofactory-cli, replace your %s environment variable", ENV_GF_TOKEN)),
+			Sprint(fmt.Sprintf("if you wish to use this with gofactory-cli, replace your %s environment variable or run gofactory token generate", ENV_GF_TOKEN)),
 	))
 }
 
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+)
+
+// optionChange is an option whose current value differs from the desired one.
+type optionChange struct {
+	key     string
+	current string
+	desired string
+}
+
+// readServerOptionsFile reads server options keyed by their FG.* wire keys from a YAML or JSON file.
+func readServerOptionsFile(path string) (api.ServerOptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return api.ServerOptions{}, fmt.Errorf("cannot read options file: %w", err)
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return api.ServerOptions{}, fmt.Errorf("cannot decode options file %s: %w", path, err)
+	}
+
+	options, err := serverOptionsFromValues(values)
+	if err != nil {
+		return api.ServerOptions{}, fmt.Errorf("invalid options file %s: %w", path, err)
+	}
+	return options, nil
+}
+
+// serverOptionsFromValues converts server options decoded from YAML and keyed by their FG.* wire keys.
+// YAML booleans and numbers are accepted, string values must be what the server expects.
+func serverOptionsFromValues(values map[string]any) (api.ServerOptions, error) {
+	wire, err := optionStrings(values)
+	if err != nil {
+		return api.ServerOptions{}, err
+	}
+
+	var options api.ServerOptions
+	if err := fromOptionMap(wire, &options); err != nil {
+		return api.ServerOptions{}, err
+	}
+	if err := options.Validate(); err != nil {
+		return api.ServerOptions{}, err
+	}
+	return options, nil
+}
+
+// optionStrings converts option values decoded from YAML to the strings the server expects,
+// writing booleans as "True" or "False".
+func optionStrings(values map[string]any) (map[string]string, error) {
+	wire := make(map[string]string, len(values))
+	for key, value := range values {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case string:
+			wire[key] = v
+		case bool:
+			wire[key] = "False"
+			if v {
+				wire[key] = "True"
+			}
+		case int:
+			wire[key] = strconv.Itoa(v)
+		case float64:
+			wire[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return nil, fmt.Errorf("option %s must be a string, number or boolean", key)
+		}
+	}
+	return wire, nil
+}
+
+// toOptionMap returns the options of v, such as api.ServerOptions, keyed by their FG.* wire keys.
+func toOptionMap(v any) (map[string]string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	options := make(map[string]string)
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// fromOptionMap decodes options keyed by their FG.* wire keys into v, such as *api.ServerOptions.
+func fromOptionMap(options map[string]string, v any) error {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// diffOptions returns the desired options whose value differs from the current one, sorted by key.
+func diffOptions(current map[string]string, desired map[string]string) []optionChange {
+	var changes []optionChange
+	for key, value := range desired {
+		if current[key] != value {
+			changes = append(changes, optionChange{key: key, current: current[key], desired: value})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b optionChange) int {
+		return cmp.Compare(a.key, b.key)
+	})
+	return changes
+}
+
+// changedOptions returns the desired values of the changes, keyed by their FG.* wire keys.
+func changedOptions(changes []optionChange) map[string]string {
+	options := make(map[string]string, len(changes))
+	for _, change := range changes {
+		options[change.key] = change.desired
+	}
+	return options
+}
+
+// printOptionChanges prints the changes as "~ key: current -> desired" lines.
+func printOptionChanges(changes []optionChange) {
+	for _, change := range changes {
+		current := change.current
+		if len(current) == 0 {
+			current = "(not set)"
+		}
+		pterm.Printfln("  %s %s: %s -> %s",
+			pterm.FgYellow.Sprint("~"),
+			change.key,
+			pterm.FgRed.Sprint(current),
+			pterm.FgGreen.Sprint(change.desired))
+	}
+}
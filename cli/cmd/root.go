@@ -19,6 +19,7 @@ var (
 				Logger.Level = pterm.LogLevelTrace
 				Logger = Logger.WithCaller()
 			}
+			client = newClient()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(os.Args) == 1 {
@@ -28,7 +29,9 @@ var (
 		SilenceUsage: true,
 	}
 
-	Trace bool
+	Trace       bool
+	Insecure    bool
+	Fingerprint string
 )
 
 var (
@@ -43,6 +46,9 @@ const (
 	VERSION      = "0.0.1"
 	ENV_GF_URL   = "GF_URL"
 	ENV_GF_TOKEN = "GF_TOKEN"
+
+	// ENV_GF_ADMIN_PASSWORD is read by the bootstrap command when --admin-password is not set.
+	ENV_GF_ADMIN_PASSWORD = "GF_ADMIN_PASSWORD"
 )
 
 func init() {
@@ -51,16 +57,48 @@ func init() {
 	serverUrl = os.Getenv(ENV_GF_URL)
 	serverToken = os.Getenv(ENV_GF_TOKEN)
 
+	config, err := loadConfig()
+	if err != nil {
+		Logger.Warn("cannot load the stored configuration", Logger.Args("error", err))
+	} else {
+		if len(serverUrl) == 0 {
+			serverUrl = config.URL
+		}
+		if len(serverToken) == 0 {
+			serverToken = config.Token
+		}
+	}
+
 	if len(serverToken) == 0 {
 		Logger.Warn("check for empty environment variables", Logger.Args(serverUrl, serverToken))
 	} else if len(serverUrl) == 0 {
 		Logger.Fatal("GF_URL cannot be empty")
 	}
 
-	client = api.NewGoFactoryClient(serverUrl, serverToken, true)
 	ctx = context.Background()
 
 	Root.PersistentFlags().BoolVarP(&Trace, "trace", "t", false, "set the cli to trace mode")
+	Root.PersistentFlags().BoolVarP(&Insecure, "insecure", "k", false, "skip verification of the server's certificate")
+	Root.PersistentFlags().StringVar(&Fingerprint, "fingerprint", "", "SHA-256 fingerprint the server's certificate must match")
+}
+
+func newClient() *api.GoFactoryClient {
+	options := []api.Option{
+		api.WithAuthToken(serverToken),
+		api.WithUserAgent("gofactory-cli/" + VERSION),
+	}
+
+	switch {
+	case Insecure:
+		Logger.Warn("server certificate verification is disabled")
+		options = append(options, api.WithInsecureSkipVerify(true))
+	case len(Fingerprint) != 0:
+		options = append(options, api.WithCertificatePins(Fingerprint))
+	default:
+		options = append(options, trustOnFirstUse())
+	}
+
+	return api.NewClient(serverUrl, options...)
 }
 
 func StartUi() {
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"maps"
 	"reflect"
+	"time"
 
 	"github.com/alchemicalkube/gofactory/api"
 	"github.com/pterm/pterm"
@@ -103,6 +105,14 @@ func getServerOptions() {
 		"send gameplay data", options.ServerOptions.SendGameplayData,
 		"network quality", options.ServerOptions.NetworkQuality))
 
+	if len(options.ServerOptions.Extra) > 0 {
+		extra := make(map[string]any)
+		for key, value := range options.ServerOptions.Extra {
+			extra[key] = value
+		}
+		Logger.Info("other server options", Logger.ArgsFromMap(extra))
+	}
+
 	if !reflect.ValueOf(options.PendingServerOptions).IsZero() {
 		// I f*cking love pterm.
 
@@ -111,11 +121,15 @@ func getServerOptions() {
 
 		s := reflect.ValueOf(options.PendingServerOptions)
 		for i := range s.NumField() {
-			if !s.Field(i).IsZero() {
+			if s.Field(i).Kind() == reflect.String && !s.Field(i).IsZero() {
 				pendingOptionsStyle[s.Type().Field(i).Name] = *pterm.NewStyle(pterm.FgYellow)
 				m[s.Type().Field(i).Name] = s.Field(i).String()
 			}
 		}
+		for key, value := range options.PendingServerOptions.Extra {
+			pendingOptionsStyle[key] = *pterm.NewStyle(pterm.FgYellow)
+			m[key] = value
+		}
 
 		if len(m) > 0 {
 			Logger.AppendKeyStyles(pendingOptionsStyle)
@@ -124,20 +138,137 @@ func getServerOptions() {
 	}
 }
 
+var (
+	fromFileFlag              string
+	autoPauseFlag             bool
+	autoSaveOnDisconnectFlag  bool
+	disableSeasonalEventsFlag bool
+	sendGameplayDataFlag      bool
+	autosaveIntervalFlag      time.Duration
+	serverRestartTimeSlotFlag time.Duration
+	networkQualityFlag        string
+	optionFlag                map[string]string
+)
+
 var setServerOptionsCommand = &cobra.Command{
 	Use:   "set",
 	Short: "set server options",
-	Args:  cobra.ExactArgs(1),
+	Long: "sets the server options given as flags, or read from a YAML or JSON file of FG.* options with --from-file. " +
+		"Flags take precedence over the file. The changes are shown and confirmed before they are applied.",
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		/*
-				Since there are a lot of options here, I'll need to think about how to actually apply them.
-				By standard, we will allow multiple flags to specify the server options as sometimes you may only want
-				to set one or two options. But if you want to do loads, might be worth specifying a file?
+		setServerOptions(cmd)
+	},
+}
 
-			TODO: Think carefully about this one.
+func setServerOptions(cmd *cobra.Command) {
+	desired := make(map[string]string)
+	if len(fromFileFlag) != 0 {
+		options, err := readServerOptionsFile(fromFileFlag)
+		if err != nil {
+			Logger.Fatal("cannot read server options", Logger.Args("error", err))
+		}
+		desired, err = toOptionMap(options)
+		if err != nil {
+			Logger.Fatal("cannot read server options", Logger.Args("error", err))
+		}
+	}
 
-		*/
-	},
+	flagOptions, err := serverOptionsFromFlags(cmd)
+	if err != nil {
+		Logger.Fatal("invalid server options", Logger.Args("error", err))
+	}
+	maps.Copy(desired, flagOptions)
+
+	if len(desired) == 0 {
+		Logger.Fatal("no server options to set, pass option flags or --from-file")
+	}
+
+	options, err := client.GetServerOptions(ctx)
+	if err != nil {
+		Logger.Fatal("get server options error", Logger.Args("error", err))
+	}
+	current, err := toOptionMap(options.ServerOptions)
+	if err != nil {
+		Logger.Fatal("cannot read server options", Logger.Args("error", err))
+	}
+	pending, err := toOptionMap(options.PendingServerOptions)
+	if err != nil {
+		Logger.Fatal("cannot read server options", Logger.Args("error", err))
+	}
+	maps.Copy(current, pending)
+
+	changes := diffOptions(current, desired)
+	if len(changes) == 0 {
+		Logger.Info("server options are already up to date")
+		return
+	}
+
+	Logger.Info("server options to change")
+	printOptionChanges(changes)
+
+	if !yesFlag {
+		confirmed, err := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			Show(fmt.Sprintf("Apply %d server option changes?", len(changes)))
+		if err != nil {
+			Logger.Fatal("cannot confirm", Logger.Args("error", err))
+		}
+		if !confirmed {
+			Logger.Info("no server options were changed")
+			return
+		}
+	}
+
+	var update api.ServerOptions
+	if err := fromOptionMap(changedOptions(changes), &update); err != nil {
+		Logger.Fatal("cannot encode server options", Logger.Args("error", err))
+	}
+	if err := client.ApplyServerOptions(ctx, update); err != nil {
+		Logger.Fatal("cannot apply server options", Logger.Args("error", err))
+	}
+	Logger.Info("server options applied", Logger.Args("changes", len(changes)))
+
+	getServerOptions()
+}
+
+// serverOptionsFromFlags returns the server options set with flags, keyed by their FG.* wire keys.
+func serverOptionsFromFlags(cmd *cobra.Command) (map[string]string, error) {
+	flags := cmd.Flags()
+
+	var typed api.TypedServerOptions
+	if flags.Changed("auto-pause") {
+		typed.AutoPause = api.Ptr(autoPauseFlag)
+	}
+	if flags.Changed("auto-save-on-disconnect") {
+		typed.AutoSaveOnDisconnect = api.Ptr(autoSaveOnDisconnectFlag)
+	}
+	if flags.Changed("disable-seasonal-events") {
+		typed.DisableSeasonalEvents = api.Ptr(disableSeasonalEventsFlag)
+	}
+	if flags.Changed("send-gameplay-data") {
+		typed.SendGameplayData = api.Ptr(sendGameplayDataFlag)
+	}
+	if flags.Changed("autosave-interval") {
+		typed.AutosaveInterval = api.Ptr(autosaveIntervalFlag)
+	}
+	if flags.Changed("server-restart-time-slot") {
+		typed.ServerRestartTimeSlot = api.Ptr(serverRestartTimeSlotFlag)
+	}
+	if flags.Changed("network-quality") {
+		quality, err := api.ParseNetworkQuality(networkQualityFlag)
+		if err != nil {
+			return nil, err
+		}
+		typed.NetworkQuality = &quality
+	}
+	typed.Extra = optionFlag
+
+	options, err := typed.ServerOptions()
+	if err != nil {
+		return nil, err
+	}
+	return toOptionMap(options)
 }
 
 var serverNameFlag string
@@ -175,7 +306,7 @@ func claimServer(serverName string, password string) {
 		Logger.Fatal("you must specify --password and --name")
 	}
 
-	if len(client.Token) != 0 {
+	if len(client.Token()) != 0 {
 		Logger.Fatal("your GF_TOKEN environment variable is not empty")
 	}
 
@@ -184,12 +315,17 @@ func claimServer(serverName string, password string) {
 		AdminPassword: password,
 	}
 
-	err := client.ClaimServer(ctx, claimData)
+	err := client.PasswordlessLogin(ctx, api.INITIAL_ADMIN_PRIVILEGE)
+	if err != nil {
+		Logger.Fatal("cannot log in to the unclaimed server", Logger.Args("error", err))
+	}
+
+	err = client.ClaimServer(ctx, claimData)
 	if err != nil {
 		Logger.Fatal(err.Error())
 	}
 
-	Logger.Info("server claimed", Logger.Args("server name:", serverName, "password", password, "token", client.Token))
+	Logger.Info("server claimed", Logger.Args("server name:", serverName, "password", password, "token", client.Token()))
 	Logger.Warn("make sure to update your GF_TOKEN environment variable with the new one!")
 }
 
@@ -266,10 +402,12 @@ func commandRunner(command string) {
 			Logger.Fatal(err.Error())
 		}
 	} else {
-		err := client.RunServerCommand(ctx, command)
+		result, err := client.RunCommand(ctx, command)
 		if err != nil {
 			Logger.Fatal(err.Error())
 		}
+		Logger.Info("successful", Logger.Args("command", command, "output", result.Output))
+		return
 	}
 	Logger.Info("successful", Logger.Args("command", command))
 }
@@ -290,6 +428,17 @@ func init() {
 	serverOptionsCommand.AddCommand(getServerOptionsCommand)
 	serverOptionsCommand.AddCommand(setServerOptionsCommand)
 
+	setServerOptionsCommand.Flags().StringVarP(&fromFileFlag, "from-file", "f", "", "YAML or JSON file of FG.* server options to set")
+	setServerOptionsCommand.Flags().BoolVar(&autoPauseFlag, "auto-pause", false, "pause the server when no-one is connected")
+	setServerOptionsCommand.Flags().BoolVar(&autoSaveOnDisconnectFlag, "auto-save-on-disconnect", false, "save the game when a player disconnects")
+	setServerOptionsCommand.Flags().BoolVar(&disableSeasonalEventsFlag, "disable-seasonal-events", false, "disable seasonal events")
+	setServerOptionsCommand.Flags().BoolVar(&sendGameplayDataFlag, "send-gameplay-data", false, "send gameplay data to Ficsit")
+	setServerOptionsCommand.Flags().DurationVar(&autosaveIntervalFlag, "autosave-interval", 0, "interval between autosaves, such as 5m")
+	setServerOptionsCommand.Flags().DurationVar(&serverRestartTimeSlotFlag, "server-restart-time-slot", 0, "time after midnight of the scheduled restart, such as 4h30m")
+	setServerOptionsCommand.Flags().StringVar(&networkQualityFlag, "network-quality", "", "network quality: low, medium, high or ultra")
+	setServerOptionsCommand.Flags().StringToStringVar(&optionFlag, "option", nil, "set any option by its FG.* key, such as FG.MyMod.Option=True")
+	setServerOptionsCommand.Flags().BoolVarP(&yesFlag, "yes", "y", false, "do not ask for confirmation")
+
 	setPasswordCommand.AddCommand(setAdminPasswordCommand)
 	setPasswordCommand.AddCommand(setClientPasswordCommand)
 }
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+)
+
+var (
+	noSaveFlag bool
+	yesFlag    bool
+)
+
+var tokenCommand = &cobra.Command{
+	Use:   "token",
+	Short: "command to manage the server's authentication tokens",
+}
+
+var generateTokenCommand = &cobra.Command{
+	Use:   "generate",
+	Short: "generate a new api token and store it in the cli configuration",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		generateToken()
+	},
+}
+
+func generateToken() {
+	result, err := client.GenerateAPIToken(ctx)
+	Logger.Trace("generate token", Logger.Args("result", result))
+	if err != nil {
+		Logger.Fatal("cannot generate an api token", Logger.Args("error", err))
+	}
+
+	if noSaveFlag {
+		Logger.Info("api token generated", Logger.Args(
+			"privilege", result.Claims.Privilege,
+			"token", result.Token,
+		))
+		return
+	}
+
+	path := storeToken(result.Token)
+	Logger.Info("api token generated and stored", Logger.Args(
+		"privilege", result.Claims.Privilege,
+		"config", path,
+	))
+}
+
+// storeToken writes the token and the current server url into the cli configuration, warning when the
+// stored token is not used.
+func storeToken(token string) string {
+	path, err := saveToken(token)
+	if err != nil {
+		Logger.Fatal("cannot store the token", Logger.Args("error", err))
+	}
+	warnTokenOverridden(path)
+	return path
+}
+
+// storeReplacementToken stores a token that replaced the one the cli was using, such as after the admin
+// password changed. The token is printed instead if it cannot be stored, so that it is not lost.
+func storeReplacementToken(token string) {
+	path, err := saveToken(token)
+	if err != nil {
+		Logger.Warn("cannot store the new token, replace your token with it", Logger.Args(
+			"error", err,
+			"token", token,
+		))
+		return
+	}
+
+	if !warnTokenOverridden(path) {
+		Logger.Info("the new token was stored", Logger.Args("config", path))
+	}
+}
+
+// warnTokenOverridden warns when the token stored at path is not used because the GF_TOKEN environment
+// variable takes precedence over it, and reports whether it did.
+func warnTokenOverridden(path string) bool {
+	if len(os.Getenv(ENV_GF_TOKEN)) == 0 {
+		return false
+	}
+	Logger.Warn("the new token was stored, but the "+ENV_GF_TOKEN+" environment variable takes precedence over it, "+
+		"unset it or replace it with the new token", Logger.Args("config", path))
+	return true
+}
+
+// saveToken writes the token and the current server url into the cli configuration, and returns its path.
+func saveToken(token string) (string, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return "", fmt.Errorf("cannot load the stored configuration: %w", err)
+	}
+
+	config.URL = serverUrl
+	config.Token = token
+	return config.save()
+}
+
+var invalidateAllTokensCommand = &cobra.Command{
+	Use:   "invalidate-all",
+	Short: "invalidate every api token issued by the server",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		invalidateAllTokens()
+	},
+}
+
+func invalidateAllTokens() {
+	if !yesFlag {
+		confirmed, err := pterm.DefaultInteractiveConfirm.
+			WithDefaultValue(false).
+			Show("This invalidates every api token issued by the server, continue?")
+		if err != nil {
+			Logger.Fatal("cannot confirm", Logger.Args("error", err))
+		}
+		if !confirmed {
+			Logger.Info("no tokens were invalidated")
+			return
+		}
+	}
+
+	err := client.InvalidateAllAPITokens(ctx)
+	if err != nil {
+		Logger.Fatal("cannot invalidate api tokens", Logger.Args("error", err))
+	}
+	Logger.Info("all api tokens have been invalidated")
+
+	config, err := loadConfig()
+	if err != nil {
+		Logger.Warn("cannot load the stored configuration", Logger.Args("error", err))
+		return
+	}
+	claims, err := api.ParseToken(config.Token)
+	if err != nil || claims.Privilege != api.API_TOKEN_PRIVILEGE {
+		return
+	}
+
+	config.Token = ""
+	if _, err := config.save(); err != nil {
+		Logger.Warn("cannot remove the invalidated token from the stored configuration", Logger.Args("error", err))
+		return
+	}
+	Logger.Info("removed the invalidated token from the stored configuration")
+}
+
+var inspectTokenCommand = &cobra.Command{
+	Use:   "inspect [token]",
+	Short: "decode the privilege and claims of a token without contacting the server",
+	Long:  "decodes the given token, or the token in use when none is given",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		token := serverToken
+		if len(args) == 1 {
+			token = args[0]
+		}
+		inspectToken(token)
+	},
+}
+
+func inspectToken(token string) {
+	if len(token) == 0 {
+		Logger.Fatal("no token to inspect, pass one or set " + ENV_GF_TOKEN)
+	}
+
+	claims, err := api.ParseToken(token)
+	if err != nil {
+		Logger.Fatal("cannot decode the token", Logger.Args("error", err))
+	}
+
+	Logger.Info("token claims", Logger.Args(
+		"privilege", claims.Privilege,
+		"claims", claims.Claims,
+		"signature", claims.Signature,
+	))
+}
+
+var verifyTokenCommand = &cobra.Command{
+	Use:   "verify",
+	Short: "check with the server that the token in use is valid",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		verifyToken()
+	},
+}
+
+func verifyToken() {
+	claims, err := client.VerifyToken(ctx)
+	if errors.Is(err, api.ErrInvalidToken) {
+		Logger.Fatal("the token is not valid, it may have been invalidated")
+	}
+	if err != nil {
+		Logger.Fatal("cannot verify the token", Logger.Args("error", err))
+	}
+
+	Logger.Info("the token is valid", Logger.Args("privilege", claims.Privilege))
+}
+
+func init() {
+	Root.AddCommand(tokenCommand)
+
+	generateTokenCommand.Flags().BoolVar(&noSaveFlag, "no-save", false, "print the token instead of storing it")
+	invalidateAllTokensCommand.Flags().BoolVarP(&yesFlag, "yes", "y", false, "do not ask for confirmation")
+
+	tokenCommand.AddCommand(generateTokenCommand)
+	tokenCommand.AddCommand(invalidateAllTokensCommand)
+	tokenCommand.AddCommand(inspectTokenCommand)
+	tokenCommand.AddCommand(verifyTokenCommand)
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/alchemicalkube/gofactory/api"
+	"github.com/pterm/pterm"
+)
+
+func trustOnFirstUse() api.Option {
+	path, err := api.DefaultKnownHostsPath()
+	if err != nil {
+		Logger.Fatal("cannot find the known hosts file location", Logger.Args("error", err))
+	}
+
+	Logger.Trace("known hosts", Logger.Args("path", path))
+
+	return api.WithTrustOnFirstUse(api.NewKnownHosts(path), confirmFingerprint)
+}
+
+func confirmFingerprint(host string, fingerprint string) bool {
+	Logger.Warn("the server's certificate has not been seen before, make sure the fingerprint matches your server", Logger.Args(
+		"host", host,
+		"fingerprint", api.FormatFingerprint(fingerprint),
+	))
+
+	trusted, err := pterm.DefaultInteractiveConfirm.
+		WithDefaultValue(false).
+		Show("Do you trust this certificate?")
+	if err != nil {
+		Logger.Error("cannot confirm the certificate", Logger.Args("error", err))
+		return false
+	}
+
+	return trusted
+}
@@ -6,6 +6,7 @@ require (
 	github.com/alchemicalkube/gofactory/api v1.0.0
 	github.com/pterm/pterm v0.12.80
 	github.com/spf13/cobra v1.9.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (